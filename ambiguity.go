@@ -0,0 +1,76 @@
+package autoimport
+
+import (
+	"strings"
+)
+
+// Preferences decide which class is imported when a class name is ambiguous, like "Date",
+// which can be both "java.util.Date" and "java.sql.Date"
+type Preferences struct {
+	Classes  map[string]string `json:"classes,omitempty"`  // from class name to the preferred class path or package
+	Packages []string          `json:"packages,omitempty"` // the preferred packages, most preferred first
+}
+
+// inPackage checks if the given class path is the given class path or package, or is in it or in a sub-package
+func inPackage(classPath, prefix string) bool {
+	return classPath == prefix || strings.HasPrefix(classPath, prefix+".")
+}
+
+// choose returns the preferred class path of the given class paths for the given class name,
+// or an empty string if none of the preferences apply
+func (prefs *Preferences) choose(className string, classPaths []string) string {
+	if prefs == nil {
+		return ""
+	}
+	if prefix, ok := prefs.Classes[className]; ok {
+		for _, classPath := range classPaths {
+			if inPackage(classPath, prefix) {
+				return classPath
+			}
+		}
+	}
+	for _, prefix := range prefs.Packages {
+		for _, classPath := range classPaths {
+			if inPackage(classPath, prefix) {
+				return classPath
+			}
+		}
+	}
+	return ""
+}
+
+// chooseClass returns the class path that should be imported for the given class name in the given source code.
+// A class that is already imported by one of the existing imports is used first, and then the preferred class,
+// if any of the Preferences apply. Otherwise the class that the Ranker scores the highest for the source code is used,
+// and if there are classes with the same name in other packages, all of them are returned as well, best ranked first,
+// since the class name is ambiguous.
+// Classes in internal packages, like "sun.", do not make a class name ambiguous.
+func (ima *ImportMatcher) chooseClass(src *parsedSource, className string) (string, []string) {
+	classPaths := ima.ClassPaths(className)
+	if len(classPaths) == 0 {
+		return "", nil
+	}
+	for _, classPath := range classPaths {
+		for _, stmt := range src.imports {
+			if !stmt.static && (stmt.path == classPath || stmt.path == starPathOf(classPath)) {
+				return classPath, nil
+			}
+		}
+	}
+	if classPath := ima.Preferences.choose(className, classPaths); classPath != "" {
+		return classPath, nil
+	}
+	classPaths = ima.rankClassPaths(classPaths, ima.rankContext(src))
+	var candidates []string
+	packages := make(map[string]bool)
+	for _, classPath := range classPaths {
+		if !isInternal(classPath) {
+			candidates = append(candidates, classPath)
+			packages[starPathOf(classPath)] = true
+		}
+	}
+	if len(packages) < 2 {
+		return classPaths[0], nil
+	}
+	return classPaths[0], candidates
+}
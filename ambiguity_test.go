@@ -0,0 +1,75 @@
+package autoimport
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAmbiguousImports(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "rt.jar"), nil,
+		"java/util/Date.class",
+		"java/sql/Date.class",
+		"java/util/Timer.class",
+		"javax/swing/Timer.class",
+		"java/time/Instant.class",
+		"sun/misc/Instant.class",
+	)
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+
+	source := `package main;
+
+class Main {
+    Date date;
+    Instant now;
+}
+`
+	problems, err := ima.CheckImports([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Problem{
+		{Kind: AmbiguousImport, Line: 4, ClassName: "Date", Import: "java.sql.*", Candidates: []string{"java.sql.Date", "java.util.Date"}},
+		{Kind: MissingImport, Line: 5, ClassName: "Instant", Import: "java.time.*"},
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, problems)
+	}
+	if s := problems[0].String(); s != "Date is ambiguous (java.sql.Date, java.util.Date)" {
+		t.Errorf("Unexpected problem description: %q\n", s)
+	}
+	matches, err := ima.ImportMatches([]byte(source))
+	if err != nil || len(matches) != 2 || len(matches[0].Candidates) != 2 || matches[1].Candidates != nil {
+		t.Fatalf("Unexpected matches: %v (%v)\n", matches, err)
+	}
+
+	// A class that is already imported is not ambiguous
+	imported := strings.Replace(source, "package main;\n", "package main;\n\nimport java.util.*;\n", 1)
+	if problems, err := ima.CheckImports([]byte(imported)); err != nil || len(problems) != 1 || problems[0].ClassName != "Instant" {
+		t.Fatalf("Expected only Instant to be missing, got %v (%v)\n", problems, err)
+	}
+
+	ima.Preferences = &Preferences{Classes: map[string]string{"Date": "java.util.Date"}}
+	importBlock, err := ima.ImportBlock([]byte(source), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "import java.time.*; // Instant\nimport java.util.*; // Date"; string(importBlock) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, importBlock)
+	}
+
+	ima.Preferences = &Preferences{Packages: []string{"java.util"}}
+	timerSource := "package main;\n\nclass Main {\n    Timer timer;\n    Date date;\n}\n"
+	importBlock, err = ima.ImportBlock([]byte(timerSource), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "import java.util.*; // Date, Timer"; string(importBlock) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, importBlock)
+	}
+}
@@ -9,7 +9,15 @@ import (
 // appropriate import package paths. Ignores "java.lang." classes.
 func (impM *ImportMatcher) FindImports(sourceCode string) []string {
 	var foundImports []string
-	for _, word := range unique(extractWords(sourceCode)) {
+	// Use what can be found, even if the source code can not be fully tokenized
+	src, _ := parseSource([]byte(sourceCode), !impM.onlyJava)
+	var words []string
+	for _, typeName := range src.typeNames {
+		if !hasS(src.declaredTypes, typeName.text) {
+			words = append(words, typeName.text)
+		}
+	}
+	for _, word := range unique(words) {
 		foundPath := impM.ImportPathExact(word)
 		if foundPath == "" {
 			// fmt.Fprintf(os.Stderr, "could not find an import path for this word: %s (could be fine)\n", word)
@@ -0,0 +1,146 @@
+package autoimport
+
+import (
+	"encoding/gob"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/xyproto/env/v2"
+)
+
+// cacheVersion should be increased whenever the format of the cached data changes
+const cacheVersion = 7
+
+// cachedArchive contains the classes that were found in an archive,
+// together with the size and modification time of the archive when it was scanned
+type cachedArchive struct {
+	Size    int64
+	ModTime int64
+	Classes []ClassInfo
+}
+
+// classCache is an on-disk cache of the classes found in each archive,
+// so that only new or changed archives need to be scanned again
+type classCache struct {
+	Version  int
+	Archives map[string]*cachedArchive // map from archive path to cached classes
+
+	path    string          // where the cache is stored
+	seen    map[string]bool // archives that have been looked up or stored since the cache was loaded
+	changed bool            // has the cache been modified since it was loaded?
+	mut     sync.Mutex      // mutex for protecting the maps
+}
+
+// DefaultCachePath returns the path of the class index cache file.
+// $AUTOIMPORT_CACHE is used, if set. If not, a file in the user cache directory is used.
+func DefaultCachePath() string {
+	if cachePath := env.Str("AUTOIMPORT_CACHE"); cachePath != "" {
+		return cachePath
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		cacheDir = filepath.Join(env.HomeDir(), ".cache")
+	}
+	return filepath.Join(cacheDir, "autoimport", "classes.gob")
+}
+
+// ClearCache removes the class index cache file, so that all archives are scanned the next time
+// an ImportMatcher is created. Setting $AUTOIMPORT_REBUILD_CACHE to true has the same effect.
+func ClearCache() error {
+	if err := os.Remove(DefaultCachePath()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// loadCache reads the class index cache from the given path.
+// If rebuild is true, or if the cache can not be read, an empty cache is returned.
+func loadCache(path string, rebuild bool) *classCache {
+	cache := &classCache{
+		Version:  cacheVersion,
+		Archives: make(map[string]*cachedArchive),
+		path:     path,
+		seen:     make(map[string]bool),
+	}
+	if rebuild {
+		cache.changed = true
+		return cache
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return cache
+	}
+	defer f.Close()
+	var loaded classCache
+	if err := gob.NewDecoder(f).Decode(&loaded); err != nil || loaded.Version != cacheVersion || loaded.Archives == nil {
+		cache.changed = true
+		return cache
+	}
+	cache.Archives = loaded.Archives
+	return cache
+}
+
+// lookup returns the cached classes for the given archive, if the archive has not changed since it was scanned
+func (cache *classCache) lookup(archivePath string, fi os.FileInfo) ([]ClassInfo, bool) {
+	cache.mut.Lock()
+	defer cache.mut.Unlock()
+	cache.seen[archivePath] = true
+	cached, ok := cache.Archives[archivePath]
+	if !ok || cached.Size != fi.Size() || cached.ModTime != fi.ModTime().UnixNano() {
+		return nil, false
+	}
+	return cached.Classes, true
+}
+
+// store adds the classes that were found in the given archive to the cache
+func (cache *classCache) store(archivePath string, fi os.FileInfo, classes []ClassInfo) {
+	cache.mut.Lock()
+	defer cache.mut.Unlock()
+	cache.seen[archivePath] = true
+	cache.Archives[archivePath] = &cachedArchive{
+		Size:    fi.Size(),
+		ModTime: fi.ModTime().UnixNano(),
+		Classes: classes,
+	}
+	cache.changed = true
+}
+
+// save writes the cache to disk, if it has changed. Archives that were not seen
+// during this run and that no longer exist are removed from the cache first.
+func (cache *classCache) save() error {
+	cache.mut.Lock()
+	defer cache.mut.Unlock()
+	for archivePath := range cache.Archives {
+		if !cache.seen[archivePath] && !exists(archivePath) {
+			delete(cache.Archives, archivePath)
+			cache.changed = true
+		}
+	}
+	if !cache.changed {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(cache.path), 0o755); err != nil {
+		return err
+	}
+	// Write to a temporary file first, then rename, so that concurrent runs never see a partial cache
+	f, err := os.CreateTemp(filepath.Dir(cache.path), filepath.Base(cache.path)+".*")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(cache); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	if err := os.Rename(f.Name(), cache.path); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	cache.changed = false
+	return nil
+}
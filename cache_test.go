@@ -0,0 +1,104 @@
+package autoimport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/xyproto/env/v2"
+)
+
+func TestMain(m *testing.M) {
+	// Use a separate class index cache when testing
+	cacheDir, err := os.MkdirTemp("", "autoimport-test-cache")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("AUTOIMPORT_CACHE", filepath.Join(cacheDir, "classes.gob"))
+	code := m.Run()
+	os.RemoveAll(cacheDir)
+	os.Exit(code)
+}
+
+// setenv sets an environment variable for the duration of the test,
+// and makes sure that the env package sees the change
+func setenv(t *testing.T, name, value string) {
+	t.Helper()
+	t.Cleanup(env.Load) // cleanup functions are called in reverse order, so this is called after the variable is restored
+	t.Setenv(name, value)
+	env.Load()
+}
+
+func TestCache(t *testing.T) {
+	cachePath := filepath.Join(t.TempDir(), "classes.gob")
+	setenv(t, "AUTOIMPORT_CACHE", cachePath)
+
+	libPath := t.TempDir()
+	jarPath := filepath.Join(libPath, "example.jar")
+	writeTestArchive(t, jarPath, nil, "com/example/Widget.class")
+
+	if _, err := NewCustom([]string{libPath}, true); err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if !exists(cachePath) {
+		t.Fatalf("Expected the cache to be written to %s\n", cachePath)
+	}
+
+	// Modify the cached classes, to be able to tell if the cache is used
+	cache := loadCache(cachePath, false)
+	cached, ok := cache.Archives[jarPath]
+	if !ok || len(cached.Classes) != 1 || cached.Classes[0].Path != "com.example.Widget" {
+		t.Fatalf("Expected com.example.Widget to be cached for %s\n", jarPath)
+	}
+	cached.Classes[0].Path = "com.example.CachedWidget"
+	cache.changed = true
+	if err := cache.save(); err != nil {
+		t.Fatalf("Could not save the cache: %v\n", err)
+	}
+
+	ima, err := NewCustom([]string{libPath}, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if classPath := ima.ImportPathExact("CachedWidget"); classPath != "com.example.CachedWidget" {
+		t.Fatalf("Expected the cached class to be used, got %q\n", classPath)
+	}
+
+	// Forcing a rebuild should scan the archive again
+	setenv(t, "AUTOIMPORT_REBUILD_CACHE", "1")
+	ima, err = NewCustom([]string{libPath}, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if classPath := ima.ImportPathExact("Widget"); classPath != "com.example.Widget" {
+		t.Fatalf("Expected the archive to be scanned again, got %q\n", classPath)
+	}
+	setenv(t, "AUTOIMPORT_REBUILD_CACHE", "")
+
+	// A changed archive should also be scanned again
+	writeTestArchive(t, jarPath, nil, "com/example/Gadget.class")
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(jarPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	ima, err = NewCustom([]string{libPath}, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if classPath := ima.ImportPathExact("Gadget"); classPath != "com.example.Gadget" {
+		t.Fatalf("Expected the changed archive to be scanned again, got %q\n", classPath)
+	}
+
+	// Removed archives should be removed from the cache
+	if err := os.Remove(jarPath); err != nil {
+		t.Fatal(err)
+	}
+	writeTestArchive(t, filepath.Join(libPath, "other.jar"), nil, "com/example/Other.class")
+	if _, err := NewCustom([]string{libPath}, true); err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if _, ok := loadCache(cachePath, false).Archives[jarPath]; ok {
+		t.Fatalf("Expected %s to be removed from the cache\n", jarPath)
+	}
+}
@@ -0,0 +1,181 @@
+package autoimport
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ProblemKind is a kind of problem with the imports of a source file
+type ProblemKind int
+
+const (
+	// MissingImport is a class that is used, but not imported
+	MissingImport ProblemKind = iota
+	// UnusedImport is an import that is not used
+	UnusedImport
+	// UnorderedImports is an import that is not in the order that FixImports would place it in,
+	// which depends on the Layout
+	UnorderedImports
+	// AmbiguousImport is a class that is used, but not imported, and that exists in several packages
+	AmbiguousImport
+)
+
+// String returns a short description of the kind of problem
+func (kind ProblemKind) String() string {
+	switch kind {
+	case MissingImport:
+		return "missing import"
+	case UnusedImport:
+		return "unused import"
+	case UnorderedImports:
+		return "unordered imports"
+	case AmbiguousImport:
+		return "ambiguous import"
+	}
+	return "unknown problem"
+}
+
+// problemKindNames are the names that are used for each kind of problem in JSON
+var problemKindNames = map[ProblemKind]string{
+	MissingImport:    "missing",
+	UnusedImport:     "unused",
+	UnorderedImports: "unordered",
+	AmbiguousImport:  "ambiguous",
+}
+
+// MarshalText returns the name of the kind of problem, like "missing", for use in JSON
+func (kind ProblemKind) MarshalText() ([]byte, error) {
+	if name, ok := problemKindNames[kind]; ok {
+		return []byte(name), nil
+	}
+	return nil, fmt.Errorf("unknown problem kind: %d", int(kind))
+}
+
+// UnmarshalText parses the name of a kind of problem, like "missing"
+func (kind *ProblemKind) UnmarshalText(text []byte) error {
+	for k, name := range problemKindNames {
+		if name == string(text) {
+			*kind = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown problem kind: %q", text)
+}
+
+// Problem is a problem with the imports of a source file, as found by CheckImports
+type Problem struct {
+	Kind      ProblemKind `json:"kind"`
+	Line      int         `json:"line"`            // the line number, starting at 1
+	ClassName string      `json:"class,omitempty"` // the name of the class (or static member) that is missing an import, if any
+	Import    string      `json:"import"`          // the import path that is missing, unused or out of order
+
+	// Candidates are all the classes with the same name, for an ambiguous import. Import is the best ranked one.
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// String returns a description of the problem, without the filename and line number
+func (p Problem) String() string {
+	switch p.Kind {
+	case MissingImport:
+		return fmt.Sprintf("missing import for %s (%s)", p.ClassName, p.Import)
+	case UnusedImport:
+		return fmt.Sprintf("unused import %s", p.Import)
+	case UnorderedImports:
+		return fmt.Sprintf("import %s is not sorted", p.Import)
+	case AmbiguousImport:
+		return fmt.Sprintf("%s is ambiguous (%s)", p.ClassName, strings.Join(p.Candidates, ", "))
+	}
+	return p.Kind.String()
+}
+
+// isImportedBy checks if the given class name is made available by one of the given import statements,
+// either by importing the class (or another class with the same name), or by a wildcard import of a
+// package that contains a class with that name
+func (ima *ImportMatcher) isImportedBy(className string, imports []importStatement) bool {
+	for _, stmt := range imports {
+		if !strings.HasSuffix(stmt.path, ".*") {
+			if stmt.alias == className || (stmt.alias == "" && classNameOf(stmt.path) == className) {
+				return true
+			}
+			continue
+		}
+		if stmt.static {
+			continue
+		}
+		packageName := strings.TrimSuffix(stmt.path, ".*")
+		for _, classPath := range ima.ClassPaths(className) {
+			if classPath == packageName+"."+className {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckImports finds problems with the imports of the given Java or Kotlin source code, without changing it:
+// classes that are used but not imported, imports that are not used and imports that are not sorted
+// (or not ordered according to the Layout, if one is set).
+// Unused imports are found the same way as when RemoveUnusedImports is used.
+// The problems are sorted by line number.
+func (ima *ImportMatcher) CheckImports(data []byte) ([]Problem, error) {
+	src, err := parseSource(data, !ima.onlyJava)
+	if err != nil {
+		return nil, err
+	}
+	var problems []Problem
+
+	// Qualified names of nested classes, like "Map.Entry", need an import of the outer class
+	for _, resolved := range ima.resolveTypeNames(src, false) {
+		if ima.isImportedBy(resolved.name, src.imports) {
+			continue
+		}
+		if len(resolved.candidates) > 0 {
+			problems = append(problems, Problem{Kind: AmbiguousImport, Line: resolved.line, ClassName: resolved.name, Import: resolved.starPath, Candidates: resolved.candidates})
+		} else {
+			problems = append(problems, Problem{Kind: MissingImport, Line: resolved.line, ClassName: resolved.name, Import: resolved.starPath})
+		}
+	}
+
+	for _, member := range ima.resolveMembers(src) {
+		if !member.imported {
+			problems = append(problems, Problem{Kind: MissingImport, Line: member.line, ClassName: member.name, Import: member.importPath})
+		}
+	}
+
+	for _, stmt := range src.imports {
+		if ima.isUnusedImport(stmt, src) {
+			problems = append(problems, Problem{Kind: UnusedImport, Line: stmt.line, Import: stmt.path})
+		}
+	}
+
+	// The import lines are compared with the order that FixImports would place them in
+	lines := strings.Split(string(data), "\n")
+	var importLines []string
+	for _, stmt := range src.imports {
+		if stmt.line >= 1 && stmt.line <= len(lines) {
+			importLines = append(importLines, strings.TrimSpace(lines[stmt.line-1]))
+		}
+	}
+	position := make(map[string]int)
+	for i, line := range ima.orderImportLines(importLines, src.packageName) {
+		position[line] = i
+	}
+	previousPosition := -1
+	for _, stmt := range src.imports {
+		if stmt.line < 1 || stmt.line > len(lines) {
+			continue
+		}
+		pos := position[strings.TrimSpace(lines[stmt.line-1])]
+		if pos < previousPosition {
+			problems = append(problems, Problem{Kind: UnorderedImports, Line: stmt.line, Import: stmt.path})
+			break
+		}
+		previousPosition = pos
+	}
+
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Line < problems[j].Line
+	})
+	return problems, nil
+}
@@ -0,0 +1,89 @@
+package autoimport
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestCheckImports(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "rt.jar"), nil,
+		"java/util/ArrayList.class",
+		"java/util/HashMap.class",
+		"java/util/List.class",
+		"java/io/File.class",
+		"java/time/Instant.class",
+	)
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+
+	source := `package main;
+
+import java.util.List;
+import java.io.File;
+import java.util.*;
+
+class Main {
+    ArrayList<String> a;
+    HashMap<String, String> m;
+    Instant now;
+    List<String> l;
+}
+`
+	problems, err := ima.CheckImports([]byte(source))
+	if err != nil {
+		t.Fatalf("Could not check the imports: %v\n", err)
+	}
+	expected := []Problem{
+		{Kind: UnusedImport, Line: 4, Import: "java.io.File"},
+		{Kind: UnorderedImports, Line: 4, Import: "java.io.File"},
+		{Kind: MissingImport, Line: 10, ClassName: "Instant", Import: "java.time.*"},
+	}
+	if len(problems) != len(expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, problems)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(problems[i], expected[i]) {
+			t.Errorf("Expected %v, got %v\n", expected[i], problems[i])
+		}
+	}
+	if s := problems[2].String(); s != "missing import for Instant (java.time.*)" {
+		t.Errorf("Unexpected problem description: %q\n", s)
+	}
+
+	fixed := `package main;
+
+import java.time.*; // Instant
+import java.util.*; // ArrayList, HashMap, List
+
+class Main {
+    ArrayList<String> a;
+    HashMap<String, String> m;
+    Instant now;
+    List<String> l;
+}
+`
+	problems, err = ima.CheckImports([]byte(fixed))
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v (%v)\n", problems, err)
+	}
+}
+
+func TestProblemJSON(t *testing.T) {
+	problem := Problem{Kind: UnusedImport, Line: 3, Import: "java.io.File"}
+	data, err := json.Marshal(problem)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := `{"kind":"unused","line":3,"import":"java.io.File"}`; string(data) != expected {
+		t.Fatalf("Expected %s, got %s\n", expected, data)
+	}
+	var decoded Problem
+	if err := json.Unmarshal(data, &decoded); err != nil || !reflect.DeepEqual(decoded, problem) {
+		t.Fatalf("Expected %v, got %v (%v)\n", problem, decoded, err)
+	}
+}
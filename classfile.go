@@ -0,0 +1,569 @@
+package autoimport
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The .class file format is described in chapter 4 of the Java Virtual Machine Specification.
+// Only what is needed for finding the kind, the access flags and the static members of a class is read.
+
+const (
+	classMagic = 0xCAFEBABE
+
+	// constant pool tags
+	constantUtf8               = 1
+	constantInteger            = 3
+	constantFloat              = 4
+	constantLong               = 5
+	constantDouble             = 6
+	constantClass              = 7
+	constantString             = 8
+	constantFieldref           = 9
+	constantMethodref          = 10
+	constantInterfaceMethodref = 11
+	constantNameAndType        = 12
+	constantMethodHandle       = 15
+	constantMethodType         = 16
+	constantDynamic            = 17
+	constantInvokeDynamic      = 18
+	constantModule             = 19
+	constantPackage            = 20
+
+	// access flags
+	accPublic     = 0x0001
+	accStatic     = 0x0008
+	accBridge     = 0x0040
+	accInterface  = 0x0200
+	accSynthetic  = 0x1000
+	accAnnotation = 0x2000
+	accEnum       = 0x4000
+	accModule     = 0x8000
+
+	// the kinds of classes in the kotlin.Metadata annotation
+	kotlinFileFacade           = 2
+	kotlinMultiFileClassFacade = 4
+)
+
+// errInvalidClassFile is returned when a .class file can not be parsed
+var errInvalidClassFile = errors.New("invalid class file")
+
+// ClassKind is the kind of type that a class file contains
+type ClassKind int
+
+const (
+	// UnknownKind is used when the class file has not been read, like for classes found in src.zip
+	UnknownKind ClassKind = iota
+	// ClassType is a class
+	ClassType
+	// InterfaceType is an interface
+	InterfaceType
+	// EnumType is an enum
+	EnumType
+	// RecordType is a record
+	RecordType
+	// AnnotationType is an annotation interface
+	AnnotationType
+)
+
+// classKindNames are the names that are used for each kind of type, also in JSON
+var classKindNames = map[ClassKind]string{
+	UnknownKind:    "unknown",
+	ClassType:      "class",
+	InterfaceType:  "interface",
+	EnumType:       "enum",
+	RecordType:     "record",
+	AnnotationType: "annotation",
+}
+
+// String returns the name of the kind of type, like "interface"
+func (kind ClassKind) String() string {
+	if name, ok := classKindNames[kind]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// MarshalText returns the name of the kind of type, for use in JSON
+func (kind ClassKind) MarshalText() ([]byte, error) {
+	return []byte(kind.String()), nil
+}
+
+// UnmarshalText parses the name of a kind of type, like "interface"
+func (kind *ClassKind) UnmarshalText(text []byte) error {
+	for k, name := range classKindNames {
+		if name == string(text) {
+			*kind = k
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown class kind: %q", text)
+}
+
+// MemberInfo is a static member of a class that can be imported, like "assertEquals" in "org.junit.Assert",
+// or a top-level function or property in Kotlin, like "runBlocking" in "kotlinx.coroutines"
+type MemberInfo struct {
+	Name     string // the name of the method, field or property
+	Property bool   // true for fields and Kotlin properties, false for methods and Kotlin functions
+}
+
+// classMember is a field or a method in a .class file
+type classMember struct {
+	name       string
+	descriptor string // like "(Ljava/lang/Object;)V"
+	access     uint16
+}
+
+// innerClass is an entry in the InnerClasses attribute of a .class file
+type innerClass struct {
+	name   string // the binary class path, like "java.util.Map$Entry"
+	outer  string // the binary class path of the enclosing class, or empty for local and anonymous classes
+	access uint16
+}
+
+// classFile contains what is read from a .class file
+type classFile struct {
+	name         string // the binary class path, like "java.util.List" or "java.util.Map$Entry"
+	superName    string // the class path of the super class, like "java.lang.Record"
+	access       uint16
+	fields       []classMember
+	methods      []classMember
+	innerClasses []innerClass
+	kotlinKind   int  // the kind in the kotlin.Metadata annotation, or 0 if it is not a Kotlin class
+	deprecated   bool // annotated with @Deprecated, or with a Deprecated attribute
+}
+
+// classReader reads big endian values from the data of a .class file
+type classReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// bytes returns the next n bytes, or nil if there are not enough bytes left
+func (r *classReader) bytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		r.err = errInvalidClassFile
+		return nil
+	}
+	b := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return b
+}
+
+func (r *classReader) u1() uint8 {
+	if b := r.bytes(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (r *classReader) u2() uint16 {
+	if b := r.bytes(2); b != nil {
+		return binary.BigEndian.Uint16(b)
+	}
+	return 0
+}
+
+func (r *classReader) u4() uint32 {
+	if b := r.bytes(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+// constantPool contains the entries of the constant pool that are used
+type constantPool struct {
+	utf8     map[uint16]string
+	classes  map[uint16]uint16 // from the index of a class entry to the index of its name
+	integers map[uint16]int32
+}
+
+// str returns the string of the utf8 entry at the given index
+func (cp *constantPool) str(index uint16) string {
+	return cp.utf8[index]
+}
+
+// className returns the name of the class entry at the given index, like "java/util/List"
+func (cp *constantPool) className(index uint16) string {
+	return cp.utf8[cp.classes[index]]
+}
+
+// readConstantPool reads the constant pool of a .class file
+func readConstantPool(r *classReader) *constantPool {
+	cp := &constantPool{
+		utf8:     make(map[uint16]string),
+		classes:  make(map[uint16]uint16),
+		integers: make(map[uint16]int32),
+	}
+	count := r.u2()
+	for i := uint16(1); i < count && r.err == nil; i++ {
+		switch tag := r.u1(); tag {
+		case constantUtf8:
+			// Modified UTF-8 is the same as UTF-8 for the names that are used
+			cp.utf8[i] = string(r.bytes(int(r.u2())))
+		case constantInteger:
+			cp.integers[i] = int32(r.u4())
+		case constantFloat, constantFieldref, constantMethodref, constantInterfaceMethodref,
+			constantNameAndType, constantDynamic, constantInvokeDynamic:
+			r.bytes(4)
+		case constantLong, constantDouble:
+			// These take up two entries
+			r.bytes(8)
+			i++
+		case constantClass:
+			cp.classes[i] = r.u2()
+		case constantString, constantMethodType, constantModule, constantPackage:
+			r.bytes(2)
+		case constantMethodHandle:
+			r.bytes(3)
+		default:
+			r.err = errInvalidClassFile
+		}
+	}
+	return cp
+}
+
+// readMembers reads the fields or the methods of a .class file, skipping their attributes
+func readMembers(r *classReader, cp *constantPool) []classMember {
+	count := int(r.u2())
+	members := make([]classMember, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		access, name, descriptor := r.u2(), r.u2(), r.u2()
+		attributeCount := int(r.u2())
+		for j := 0; j < attributeCount && r.err == nil; j++ {
+			r.u2()
+			r.bytes(int(r.u4()))
+		}
+		members = append(members, classMember{name: cp.str(name), descriptor: cp.str(descriptor), access: access})
+	}
+	return members
+}
+
+// skipElementValue skips an element value of an annotation
+func skipElementValue(r *classReader) {
+	switch tag := r.u1(); tag {
+	case 'B', 'C', 'D', 'F', 'I', 'J', 'S', 'Z', 's', 'c':
+		r.u2()
+	case 'e':
+		r.bytes(4)
+	case '@':
+		skipAnnotation(r)
+	case '[':
+		count := int(r.u2())
+		for i := 0; i < count && r.err == nil; i++ {
+			skipElementValue(r)
+		}
+	default:
+		r.err = errInvalidClassFile
+	}
+}
+
+// skipAnnotation skips an annotation
+func skipAnnotation(r *classReader) {
+	r.u2()
+	count := int(r.u2())
+	for i := 0; i < count && r.err == nil; i++ {
+		r.u2()
+		skipElementValue(r)
+	}
+}
+
+// readAnnotations reads the RuntimeVisibleAnnotations attribute of a class, and returns the kind ("k")
+// of the kotlin.Metadata annotation, or 0 if there is none, and if the class is annotated with @Deprecated
+func readAnnotations(data []byte, cp *constantPool) (int, bool) {
+	r := &classReader{data: data}
+	kotlinKind, deprecated := 0, false
+	count := int(r.u2())
+	for i := 0; i < count && r.err == nil; i++ {
+		switch cp.str(r.u2()) {
+		case "Ljava/lang/Deprecated;":
+			deprecated = true
+		case "Lkotlin/Metadata;":
+			pairCount := int(r.u2())
+			for j := 0; j < pairCount && r.err == nil; j++ {
+				name := cp.str(r.u2())
+				if name == "k" && r.pos < len(r.data) && r.data[r.pos] == 'I' {
+					r.u1()
+					kotlinKind = int(cp.integers[r.u2()])
+					continue
+				}
+				skipElementValue(r)
+			}
+			continue
+		}
+		r.pos -= 2
+		skipAnnotation(r)
+	}
+	return kotlinKind, deprecated
+}
+
+// readInnerClasses reads the entries of an InnerClasses attribute
+func readInnerClasses(data []byte, cp *constantPool) []innerClass {
+	r := &classReader{data: data}
+	count := int(r.u2())
+	innerClasses := make([]innerClass, 0, count)
+	for i := 0; i < count && r.err == nil; i++ {
+		name, outer := cp.className(r.u2()), cp.className(r.u2())
+		r.u2() // the simple name
+		innerClasses = append(innerClasses, innerClass{
+			name:   strings.ReplaceAll(name, "/", "."),
+			outer:  strings.ReplaceAll(outer, "/", "."),
+			access: r.u2(),
+		})
+	}
+	return innerClasses
+}
+
+// parseClassFile reads the name, the access flags, the fields, the methods and the inner classes
+// of a .class file, if it is deprecated, and the kind of Kotlin class, if it is one
+func parseClassFile(data []byte) (*classFile, error) {
+	r := &classReader{data: data}
+	if r.u4() != classMagic {
+		return nil, errInvalidClassFile
+	}
+	r.u2() // minor version
+	r.u2() // major version
+	cp := readConstantPool(r)
+	cf := &classFile{access: r.u2()}
+	cf.name = strings.ReplaceAll(cp.className(r.u2()), "/", ".")
+	cf.superName = strings.ReplaceAll(cp.className(r.u2()), "/", ".")
+	r.bytes(2 * int(r.u2()))
+	cf.fields = readMembers(r, cp)
+	cf.methods = readMembers(r, cp)
+	attributeCount := int(r.u2())
+	for i := 0; i < attributeCount && r.err == nil; i++ {
+		name := cp.str(r.u2())
+		attribute := r.bytes(int(r.u4()))
+		switch name {
+		case "RuntimeVisibleAnnotations":
+			var deprecated bool
+			cf.kotlinKind, deprecated = readAnnotations(attribute, cp)
+			cf.deprecated = cf.deprecated || deprecated
+		case "Deprecated":
+			cf.deprecated = true
+		case "InnerClasses":
+			cf.innerClasses = readInnerClasses(attribute, cp)
+		}
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return cf, nil
+}
+
+// kind returns the kind of type that the class file contains
+func (cf *classFile) kind() ClassKind {
+	switch {
+	case cf.access&accAnnotation != 0:
+		return AnnotationType
+	case cf.access&accInterface != 0:
+		return InterfaceType
+	case cf.access&accEnum != 0:
+		return EnumType
+	case cf.superName == "java.lang.Record":
+		return RecordType
+	}
+	return ClassType
+}
+
+// isPublicNested checks if the class is a public static nested class, like "java.util.Map$Entry", that is
+// nested in public static classes only, so that it can be imported. Local and anonymous classes are not.
+// The InnerClasses attribute of a nested class also lists the classes that it is nested in.
+func (cf *classFile) isPublicNested() bool {
+	current, nested := cf.name, false
+	// Each entry can only be passed once, unless the class file is invalid
+	for range cf.innerClasses {
+		found := false
+		for _, inner := range cf.innerClasses {
+			if inner.name != current {
+				continue
+			}
+			if inner.outer == "" || inner.access&(accPublic|accStatic) != accPublic|accStatic || inner.access&accSynthetic != 0 {
+				return false
+			}
+			current, found, nested = inner.outer, true, true
+			break
+		}
+		if !found {
+			break
+		}
+	}
+	return nested
+}
+
+// isKotlinFacade checks if the class contains the top-level functions and properties of a Kotlin file
+func (cf *classFile) isKotlinFacade() bool {
+	return cf.kotlinKind == kotlinFileFacade || cf.kotlinKind == kotlinMultiFileClassFacade
+}
+
+// propertyName returns the name of the Kotlin property that the given getter method is for,
+// like "foo" for "getFoo" and "isFoo" for "isFoo", or an empty string if it is not a getter
+func propertyName(method classMember) string {
+	if !strings.HasPrefix(method.descriptor, "()") || method.descriptor == "()V" {
+		return ""
+	}
+	for _, prefix := range []string{"get", "is"} {
+		rest := strings.TrimPrefix(method.name, prefix)
+		if rest == method.name {
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(rest)
+		if !unicode.IsUpper(r) {
+			continue
+		}
+		if prefix == "is" {
+			return method.name
+		}
+		return string(unicode.ToLower(r)) + rest[size:]
+	}
+	return ""
+}
+
+// staticMembers returns the public static members of the class that can be imported.
+// For Kotlin file facades, these are the top-level functions and properties.
+func (cf *classFile) staticMembers() []MemberInfo {
+	if cf.access&accPublic == 0 {
+		return nil
+	}
+	facade := cf.isKotlinFacade()
+	var members []MemberInfo
+	seen := make(map[MemberInfo]bool)
+	add := func(member MemberInfo) {
+		if !seen[member] {
+			seen[member] = true
+			members = append(members, member)
+		}
+	}
+	isImportable := func(member classMember) bool {
+		return member.access&(accPublic|accStatic) == accPublic|accStatic &&
+			member.access&(accSynthetic|accBridge) == 0 &&
+			!strings.ContainsAny(member.name, "<$")
+	}
+	for _, method := range cf.methods {
+		if !isImportable(method) || method.name == "main" {
+			continue
+		}
+		if cf.access&accEnum != 0 && (method.name == "values" || method.name == "valueOf") {
+			continue
+		}
+		if facade {
+			if name := propertyName(method); name != "" {
+				add(MemberInfo{Name: name, Property: true})
+				continue
+			}
+			if strings.HasPrefix(method.name, "set") && strings.HasSuffix(method.descriptor, ")V") {
+				// the setter of a property
+				continue
+			}
+		}
+		add(MemberInfo{Name: method.name})
+	}
+	if facade {
+		// Only the fields of Kotlin file facades are imported, for "const val" and "@JvmField" properties
+		for _, field := range cf.fields {
+			if isImportable(field) {
+				add(MemberInfo{Name: field.name, Property: true})
+			}
+		}
+	}
+	return members
+}
+
+// classMembers parses the given .class file data and returns the importable static members,
+// and true if the class is a Kotlin file facade. Returns nil if the class file can not be parsed.
+func classMembers(data []byte) ([]MemberInfo, bool) {
+	cf, err := parseClassFile(data)
+	if err != nil {
+		return nil, false
+	}
+	return cf.staticMembers(), cf.isKotlinFacade()
+}
+
+// kotlinGeneratedNested are the names of nested classes that the Kotlin compiler generates,
+// which are not imported by name
+var kotlinGeneratedNested = []string{"Companion", "DefaultImpls", "WhenMappings"}
+
+// nestedClassPath returns the class path and the qualified name within the package of the nested class
+// in the given archive entry, like "java.util.Map.Entry" and "Map.Entry" for "java/util/Map$Entry.class".
+// Returns empty strings if the entry is a top-level class, or a local or anonymous class, like "Assert$1".
+func nestedClassPath(entryName, classPath string) (string, string) {
+	name := strings.ReplaceAll(strings.TrimSuffix(strings.TrimSuffix(entryName, ".class"), ".CLASS"), "/", ".")
+	if name == classPath || !strings.HasPrefix(name, classPath+"$") {
+		return "", ""
+	}
+	names := strings.Split(name[len(classPath)+1:], "$")
+	for _, nestedName := range names {
+		r, _ := utf8.DecodeRuneInString(nestedName)
+		if !unicode.IsUpper(r) || hasS(kotlinGeneratedNested, nestedName) {
+			return "", ""
+		}
+	}
+	qualifiedName := classNameOf(classPath) + "." + strings.Join(names, ".")
+	return strings.TrimSuffix(classPath, classNameOf(classPath)) + qualifiedName, qualifiedName
+}
+
+// classEntryInfo returns the ClassInfo for the given .class entry in an archive, using the given function for
+// reading the class file. The kind, the access flags and the static members are read from the class file.
+// Returns false if the entry should not be indexed, because it is a synthetic class, a module descriptor, or
+// a nested class that is not public and static. If the class file of a top-level class can not be read,
+// only the class path is used, while nested classes are skipped.
+func classEntryInfo(entryName string, read func() ([]byte, error)) (ClassInfo, bool) {
+	classPath := classPathFromEntry(entryName)
+	if classPath == "" {
+		return ClassInfo{}, false
+	}
+	nestedPath, qualifiedName := nestedClassPath(entryName, classPath)
+	isTopLevel := strings.ReplaceAll(strings.TrimSuffix(strings.TrimSuffix(entryName, ".class"), ".CLASS"), "/", ".") == classPath
+	if !isTopLevel && nestedPath == "" {
+		return ClassInfo{}, false
+	}
+	info := ClassInfo{Path: classPath}
+	data, err := read()
+	if err != nil {
+		return info, isTopLevel
+	}
+	cf, err := parseClassFile(data)
+	if err != nil {
+		return info, isTopLevel
+	}
+	if cf.access&(accSynthetic|accModule) != 0 {
+		return info, false
+	}
+	if !isTopLevel {
+		if !cf.isPublicNested() {
+			return ClassInfo{}, false
+		}
+		// Only the kind is used for nested classes, not the static members
+		return ClassInfo{Path: nestedPath, Nested: qualifiedName, Kind: cf.kind(), Deprecated: cf.deprecated}, true
+	}
+	info.Kind, info.Deprecated = cf.kind(), cf.deprecated
+	info.PackagePrivate = cf.access&accPublic == 0
+	if !isInternal(classPath) {
+		info.Members, info.KotlinFacade = cf.staticMembers(), cf.isKotlinFacade()
+	}
+	return info, true
+}
+
+// isDeprecatedModule checks if the given module-info.class data is the descriptor of a deprecated module,
+// like the "java.corba" module of JDK 9 and 10
+func isDeprecatedModule(data []byte) bool {
+	cf, err := parseClassFile(data)
+	return err == nil && cf.access&accModule != 0 && cf.deprecated
+}
+
+// readZipFile reads the contents of the given file in a .jar or .jmod file
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,474 @@
+package autoimport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testClassFile builds a minimal .class file for the given class path, with the given methods and fields.
+// If kotlinKind is not 0, a kotlin.Metadata annotation with that kind is added.
+// If inner classes are given, an InnerClasses attribute is added.
+func testClassFile(classPath string, access uint16, kotlinKind int, methods, fields []classMember, innerClasses ...innerClass) []byte {
+	var pool bytes.Buffer
+	count := uint16(1)
+	utf8Index := make(map[string]uint16)
+	addUtf8 := func(s string) uint16 {
+		if index, ok := utf8Index[s]; ok {
+			return index
+		}
+		pool.WriteByte(constantUtf8)
+		binary.Write(&pool, binary.BigEndian, uint16(len(s)))
+		pool.WriteString(s)
+		utf8Index[s] = count
+		count++
+		return count - 1
+	}
+	addClass := func(name string) uint16 {
+		nameIndex := addUtf8(name)
+		pool.WriteByte(constantClass)
+		binary.Write(&pool, binary.BigEndian, nameIndex)
+		count++
+		return count - 1
+	}
+	thisClass := addClass(strings.ReplaceAll(classPath, ".", "/"))
+	superClass := addClass("java/lang/Object")
+	// A long constant takes up two entries
+	pool.WriteByte(constantLong)
+	pool.Write(make([]byte, 8))
+	count += 2
+
+	writeMembers := func(buf *bytes.Buffer, members []classMember) {
+		binary.Write(buf, binary.BigEndian, uint16(len(members)))
+		for _, member := range members {
+			binary.Write(buf, binary.BigEndian, member.access)
+			binary.Write(buf, binary.BigEndian, addUtf8(member.name))
+			binary.Write(buf, binary.BigEndian, addUtf8(member.descriptor))
+			binary.Write(buf, binary.BigEndian, uint16(0))
+		}
+	}
+	var body bytes.Buffer
+	binary.Write(&body, binary.BigEndian, access)
+	binary.Write(&body, binary.BigEndian, thisClass)
+	binary.Write(&body, binary.BigEndian, superClass)
+	binary.Write(&body, binary.BigEndian, uint16(0)) // interfaces
+	writeMembers(&body, fields)
+	writeMembers(&body, methods)
+	var attributes bytes.Buffer
+	attributeCount := uint16(0)
+	if len(innerClasses) > 0 {
+		var inner bytes.Buffer
+		binary.Write(&inner, binary.BigEndian, uint16(len(innerClasses)))
+		for _, innerClass := range innerClasses {
+			binary.Write(&inner, binary.BigEndian, addClass(strings.ReplaceAll(innerClass.name, ".", "/")))
+			outerIndex := uint16(0)
+			if innerClass.outer != "" {
+				outerIndex = addClass(strings.ReplaceAll(innerClass.outer, ".", "/"))
+			}
+			binary.Write(&inner, binary.BigEndian, outerIndex)
+			binary.Write(&inner, binary.BigEndian, uint16(0))
+			binary.Write(&inner, binary.BigEndian, innerClass.access)
+		}
+		binary.Write(&attributes, binary.BigEndian, addUtf8("InnerClasses"))
+		binary.Write(&attributes, binary.BigEndian, uint32(inner.Len()))
+		attributes.Write(inner.Bytes())
+		attributeCount++
+	}
+	if kotlinKind != 0 {
+		pool.WriteByte(constantInteger)
+		binary.Write(&pool, binary.BigEndian, uint32(kotlinKind))
+		kindIndex := count
+		count++
+		var annotations bytes.Buffer
+		binary.Write(&annotations, binary.BigEndian, uint16(1))
+		binary.Write(&annotations, binary.BigEndian, addUtf8("Lkotlin/Metadata;"))
+		binary.Write(&annotations, binary.BigEndian, uint16(2))
+		// mv = [1, 9, 0]
+		binary.Write(&annotations, binary.BigEndian, addUtf8("mv"))
+		annotations.WriteByte('[')
+		binary.Write(&annotations, binary.BigEndian, uint16(3))
+		for i := 0; i < 3; i++ {
+			annotations.WriteByte('I')
+			binary.Write(&annotations, binary.BigEndian, kindIndex)
+		}
+		binary.Write(&annotations, binary.BigEndian, addUtf8("k"))
+		annotations.WriteByte('I')
+		binary.Write(&annotations, binary.BigEndian, kindIndex)
+
+		binary.Write(&attributes, binary.BigEndian, addUtf8("RuntimeVisibleAnnotations"))
+		binary.Write(&attributes, binary.BigEndian, uint32(annotations.Len()))
+		attributes.Write(annotations.Bytes())
+		attributeCount++
+	}
+	binary.Write(&body, binary.BigEndian, attributeCount)
+	body.Write(attributes.Bytes())
+
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(classMagic))
+	binary.Write(&buf, binary.BigEndian, uint32(52))
+	binary.Write(&buf, binary.BigEndian, count)
+	buf.Write(pool.Bytes())
+	buf.Write(body.Bytes())
+	return buf.Bytes()
+}
+
+func TestParseClassFile(t *testing.T) {
+	data := testClassFile("org.junit.Assert", accPublic, 0, []classMember{
+		{name: "assertEquals", descriptor: "(JJ)V", access: accPublic | accStatic},
+		{name: "assertEquals", descriptor: "(Ljava/lang/Object;Ljava/lang/Object;)V", access: accPublic | accStatic},
+		{name: "fail", descriptor: "()V", access: accPublic | accStatic},
+		{name: "format", descriptor: "()Ljava/lang/String;", access: accStatic},
+		{name: "toString", descriptor: "()Ljava/lang/String;", access: accPublic},
+		{name: "<clinit>", descriptor: "()V", access: accStatic},
+		{name: "access$000", descriptor: "()V", access: accPublic | accStatic | accSynthetic},
+	}, []classMember{
+		{name: "MAX", descriptor: "I", access: accPublic | accStatic},
+	})
+	cf, err := parseClassFile(data)
+	if err != nil {
+		t.Fatalf("Could not parse the class file: %v\n", err)
+	}
+	if cf.name != "org.junit.Assert" || cf.isKotlinFacade() {
+		t.Fatalf("Unexpected class: %s (Kotlin kind %d)\n", cf.name, cf.kotlinKind)
+	}
+	expected := []MemberInfo{{Name: "assertEquals"}, {Name: "fail"}}
+	if members := cf.staticMembers(); len(members) != len(expected) || members[0] != expected[0] || members[1] != expected[1] {
+		t.Fatalf("Expected %v, got %v\n", expected, members)
+	}
+
+	if _, err := parseClassFile(data[:len(data)-3]); err == nil {
+		t.Fatalf("Expected an error for a truncated class file\n")
+	}
+	if members, _ := classMembers(nil); members != nil {
+		t.Fatalf("Expected no members for an empty class file\n")
+	}
+}
+
+func TestKotlinFacade(t *testing.T) {
+	data := testClassFile("kotlinx.coroutines.BuildersKt", accPublic, kotlinFileFacade, []classMember{
+		{name: "runBlocking", descriptor: "(Lkotlin/jvm/functions/Function2;)Ljava/lang/Object;", access: accPublic | accStatic},
+		{name: "getDefaultTimeout", descriptor: "()J", access: accPublic | accStatic},
+		{name: "setDefaultTimeout", descriptor: "(J)V", access: accPublic | accStatic},
+		{name: "isActive", descriptor: "()Z", access: accPublic | accStatic},
+	}, []classMember{
+		{name: "MAX_DEPTH", descriptor: "I", access: accPublic | accStatic},
+	})
+	members, facade := classMembers(data)
+	if !facade {
+		t.Fatalf("Expected a Kotlin file facade\n")
+	}
+	var names []string
+	for _, member := range members {
+		names = append(names, member.Name+":"+map[bool]string{true: "property", false: "function"}[member.Property])
+	}
+	sort.Strings(names)
+	if got := strings.Join(names, ","); got != "MAX_DEPTH:property,defaultTimeout:property,isActive:property,runBlocking:function" {
+		t.Fatalf("Unexpected members: %s\n", got)
+	}
+}
+
+func TestStaticImports(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestJAR(t, filepath.Join(libPath, "lib.jar"), map[string][]byte{
+		"org/junit/Assert.class": testClassFile("org.junit.Assert", accPublic, 0, []classMember{
+			{name: "assertEquals", descriptor: "(JJ)V", access: accPublic | accStatic},
+		}, nil),
+		"org/junit/Test.class": testClassFile("org.junit.Test", accPublic, 0, nil, nil),
+		"junit/framework/Assert.class": testClassFile("junit.framework.Assert", accPublic, 0, []classMember{
+			{name: "assertEquals", descriptor: "(JJ)V", access: accPublic | accStatic},
+		}, nil),
+		"java/util/stream/Collectors.class": testClassFile("java.util.stream.Collectors", accPublic, 0, []classMember{
+			{name: "toList", descriptor: "()Ljava/util/stream/Collector;", access: accPublic | accStatic},
+		}, nil),
+		"kotlinx/coroutines/BuildersKt.class": testClassFile("kotlinx.coroutines.BuildersKt", accPublic, kotlinFileFacade, []classMember{
+			{name: "runBlocking", descriptor: "(Lkotlin/jvm/functions/Function2;)Ljava/lang/Object;", access: accPublic | accStatic},
+		}, nil),
+		"kotlin/collections/CollectionsKt.class": testClassFile("kotlin.collections.CollectionsKt", accPublic, kotlinFileFacade, []classMember{
+			{name: "listOf", descriptor: "([Ljava/lang/Object;)Ljava/util/List;", access: accPublic | accStatic},
+		}, nil),
+	})
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+
+	source := `package main;
+
+import org.junit.Test;
+
+class MainTest {
+    @Test
+    void test() {
+        assertEquals(2, add(1, 1));
+        Object list = values().stream().collect(toList());
+    }
+
+    int add(int a, int b) {
+        return a + b;
+    }
+}
+`
+	importBlock, err := ima.ImportBlock([]byte(source), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "import org.junit.*; // Test\nimport static java.util.stream.Collectors.toList;\nimport static org.junit.Assert.assertEquals;"
+	if string(importBlock) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, importBlock)
+	}
+
+	problems, err := ima.CheckImports([]byte(source))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 2 || problems[0].ClassName != "assertEquals" || problems[0].Import != "org.junit.Assert.assertEquals" || problems[1].ClassName != "toList" {
+		t.Fatalf("Unexpected problems: %v\n", problems)
+	}
+	imported := strings.Replace(source, "import org.junit.Test;", "import org.junit.Test;\nimport static java.util.stream.Collectors.toList;\nimport static org.junit.Assert.*;", 1)
+	if problems, err := ima.CheckImports([]byte(imported)); err != nil || len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v (%v)\n", problems, err)
+	}
+
+	kotlinSource := `package main
+
+fun main() = runBlocking {
+    val numbers = listOf(1, 2, 3)
+    assertEquals(3, numbers.size)
+}
+`
+	kotlinImportBlock, err := ima.ForLanguage(Kotlin).ImportBlock([]byte(kotlinSource), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = "import kotlinx.coroutines.runBlocking;\nimport org.junit.Assert.assertEquals;"
+	if string(kotlinImportBlock) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, kotlinImportBlock)
+	}
+}
+
+func TestInheritedMembers(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestJAR(t, filepath.Join(libPath, "lib.jar"), map[string][]byte{
+		"java/util/ArrayList.class": testClassFile("java.util.ArrayList", accPublic, 0, nil, nil),
+		"java/util/Objects.class": testClassFile("java.util.Objects", accPublic, 0, []classMember{
+			{name: "toString", descriptor: "(Ljava/lang/Object;)Ljava/lang/String;", access: accPublic | accStatic},
+		}, nil),
+		"java/awt/AWTEventMulticaster.class": testClassFile("java.awt.AWTEventMulticaster", accPublic, 0, []classMember{
+			{name: "add", descriptor: "(Ljava/awt/event/ActionListener;Ljava/awt/event/ActionListener;)Ljava/awt/event/ActionListener;", access: accPublic | accStatic},
+		}, nil),
+		"kotlinx/coroutines/CoroutineNameKt.class": testClassFile("kotlinx.coroutines.CoroutineNameKt", accPublic, kotlinFileFacade, []classMember{
+			{name: "getName", descriptor: "()Ljava/lang/String;", access: accPublic | accStatic},
+		}, nil),
+	})
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+
+	for _, source := range []string{
+		"import java.util.ArrayList;\n\nclass MyList extends ArrayList<String> {\n    void fill() {\n        add(\"x\");\n        System.out.println(toString());\n    }\n}\n",
+		"class Main {\n    Runnable task = new Runnable() {\n        public void run() { add(this); }\n    };\n}\n",
+		"class Main {\n    public String describe() { return toString(); }\n}\n",
+	} {
+		importBlock, err := ima.ImportBlock([]byte(source), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(importBlock), "import static") {
+			t.Errorf("Expected no static imports for inherited members in\n%s\ngot\n%s\n", source, importBlock)
+		}
+		if problems, err := ima.CheckImports([]byte(source)); err != nil || len(problems) != 0 {
+			t.Errorf("Expected no problems for inherited members in\n%s\ngot %v (%v)\n", source, problems, err)
+		}
+	}
+
+	// A member that is already imported is still used, even if the class extends another class
+	source := "import java.util.ArrayList;\nimport static java.awt.AWTEventMulticaster.*;\n\nclass MyList extends ArrayList<String> {\n    void fill() {\n        add(\"x\");\n    }\n}\n"
+	if problems, err := ima.CheckImports([]byte(source)); err != nil || len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v (%v)\n", problems, err)
+	}
+	if importBlock, err := ima.ImportBlock([]byte("class Main {\n    void fill() {\n        add(null, null);\n    }\n}\n"), false); err != nil || string(importBlock) != "import static java.awt.AWTEventMulticaster.add;" {
+		t.Fatalf("Expected a static import for a class without supertypes, got %q (%v)\n", importBlock, err)
+	}
+
+	kotlin := ima.ForLanguage(Kotlin)
+	kotlinSource := "package main\n\nclass Foo : Base() {\n    fun f() {\n        println(name)\n    }\n}\n"
+	importBlock, err := kotlin.ImportBlock([]byte(kotlinSource), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(importBlock) != 0 {
+		t.Fatalf("Expected no imports for a property that may be inherited, got\n%s\n", importBlock)
+	}
+	importBlock, err = kotlin.ImportBlock([]byte("package main\n\nfun f() {\n    println(name)\n}\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(importBlock) != 0 {
+		t.Fatalf("Expected no imports for a bare reference, got\n%s\n", importBlock)
+	}
+	kotlinSource = "package main\n\nimport kotlinx.coroutines.*\n\nfun f() {\n    println(name)\n}\n"
+	src, err := parseSource([]byte(kotlinSource), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if members := kotlin.resolveMembers(src); len(members) != 1 || members[0].importPath != "kotlinx.coroutines.name" || !members[0].imported {
+		t.Fatalf("Expected a property that is imported with a wildcard import to be found, got %v\n", members)
+	}
+}
+
+func TestClassKind(t *testing.T) {
+	tests := []struct {
+		cf       classFile
+		expected ClassKind
+	}{
+		{classFile{access: accPublic, superName: "java.lang.Object"}, ClassType},
+		{classFile{access: accPublic | accInterface}, InterfaceType},
+		{classFile{access: accPublic | accInterface | accAnnotation}, AnnotationType},
+		{classFile{access: accPublic | accEnum, superName: "java.lang.Enum"}, EnumType},
+		{classFile{access: accPublic, superName: "java.lang.Record"}, RecordType},
+	}
+	for _, test := range tests {
+		if kind := test.cf.kind(); kind != test.expected {
+			t.Errorf("Expected %s, got %s\n", test.expected, kind)
+		}
+		var kind ClassKind
+		if text, _ := test.expected.MarshalText(); kind.UnmarshalText(text) != nil || kind != test.expected {
+			t.Errorf("Could not unmarshal %q\n", text)
+		}
+	}
+}
+
+func TestClassAccess(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestJAR(t, filepath.Join(libPath, "lib.jar"), map[string][]byte{
+		"com/example/Widget.class":         testClassFile("com.example.Widget", accPublic, 0, nil, nil),
+		"com/example/Widget$1.class":       testClassFile("com.example.Widget$1", 0, 0, nil, nil),
+		"com/example/Helper.class":         testClassFile("com.example.Helper", 0, 0, nil, nil),
+		"com/example/Renderer.class":       testClassFile("com.example.Renderer", accPublic|accInterface, 0, nil, nil),
+		"com/example/Generated.class":      testClassFile("com.example.Generated", accPublic|accSynthetic, 0, nil, nil),
+		"org/example/Helper.class":         testClassFile("org.example.Helper", accPublic, 0, nil, nil),
+		"com/example/module-info.class":    testClassFile("module-info", accModule, 0, nil, nil),
+		"com/example/internal/Cache.class": {}, // can not be parsed, but the class is still indexed
+	})
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if len(ima.ClassPaths("Widget")) != 1 || len(ima.ClassPaths("Generated")) != 0 || len(ima.ClassPaths("Cache")) != 1 {
+		t.Fatalf("Unexpected classes: %v\n", ima.ClassMap())
+	}
+	if classPaths := ima.ClassPaths("Helper"); len(classPaths) != 1 || classPaths[0] != "org.example.Helper" {
+		t.Fatalf("Expected only the public Helper class, got %v\n", classPaths)
+	}
+	if matches := ima.MatchesExact("Renderer"); len(matches) != 1 || matches[0].Kind != InterfaceType {
+		t.Fatalf("Expected Renderer to be an interface, got %v\n", matches)
+	}
+
+	source := "package com.example;\n\nclass Main {\n    Helper helper;\n    Widget widget;\n}\n"
+	importBlock, err := ima.ImportBlock([]byte(source), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(importBlock) != 0 {
+		t.Fatalf("Expected no imports for classes in the same package, got %s\n", importBlock)
+	}
+	source = strings.Replace(source, "package com.example;", "package com.example.app;", 1)
+	importBlock, err = ima.ImportBlock([]byte(source), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "import com.example.*; // Widget\nimport org.example.*; // Helper"; string(importBlock) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, importBlock)
+	}
+}
+
+func TestNestedClasses(t *testing.T) {
+	const publicStatic = accPublic | accStatic
+	libPath := t.TempDir()
+	writeTestJAR(t, filepath.Join(libPath, "lib.jar"), map[string][]byte{
+		"java/util/Map.class": testClassFile("java.util.Map", accPublic|accInterface, 0, nil, nil),
+		"java/util/Map$Entry.class": testClassFile("java.util.Map$Entry", accPublic|accInterface, 0, nil, nil,
+			innerClass{name: "java.util.Map$Entry", outer: "java.util.Map", access: publicStatic | accInterface}),
+		"java/util/Map$1.class": testClassFile("java.util.Map$1", 0, 0, nil, nil,
+			innerClass{name: "java.util.Map$1", access: accStatic}),
+		"com/example/Dialog.class": testClassFile("com.example.Dialog", accPublic, 0, nil, nil),
+		"org/example/Dialog.class": testClassFile("org.example.Dialog", accPublic, 0, nil, nil),
+		"org/example/Dialog$Builder.class": testClassFile("org.example.Dialog$Builder", accPublic, 0, nil, nil,
+			innerClass{name: "org.example.Dialog$Builder", outer: "org.example.Dialog", access: publicStatic}),
+		"org/example/Dialog$Cache.class": testClassFile("org.example.Dialog$Cache", 0, 0, nil, nil,
+			innerClass{name: "org.example.Dialog$Cache", outer: "org.example.Dialog", access: accStatic}),
+		"org/example/Dialog$Builder$Options.class": testClassFile("org.example.Dialog$Builder$Options", accPublic, 0, nil, nil,
+			innerClass{name: "org.example.Dialog$Builder$Options", outer: "org.example.Dialog$Builder", access: publicStatic},
+			innerClass{name: "org.example.Dialog$Builder", outer: "org.example.Dialog", access: accPublic}),
+	})
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if classPaths := ima.ClassPaths("Map.Entry"); len(classPaths) != 1 || classPaths[0] != "java.util.Map.Entry" || len(ima.ClassPaths("Entry")) != 1 {
+		t.Fatalf("Expected Map.Entry to be indexed, got %v\n", classPaths)
+	}
+	if len(ima.ClassPaths("Cache")) != 0 || len(ima.ClassPaths("Options")) != 0 {
+		t.Fatalf("Expected nested classes that are not public and static to be skipped: %v\n", ima.ClassMap())
+	}
+	if matches := ima.MatchesExact("Entry"); len(matches) != 1 || matches[0].Package != "java.util" || matches[0].Outer != "java.util.Map" || matches[0].Import != "java.util.Map.*" || matches[0].Kind != InterfaceType {
+		t.Fatalf("Unexpected matches: %v\n", matches)
+	}
+	if matches := ima.Matches("Ma"); len(matches) != 1 || matches[0].Name != "Map" {
+		t.Fatalf("Expected only Map to match Ma, got %v\n", matches)
+	}
+
+	source := `package main;
+
+class Main {
+    Map.Entry<String, Dialog.Builder> entry;
+    Dialog.Builder builder = new Dialog.Builder();
+}
+`
+	importBlock, err := ima.ImportBlock([]byte(source), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "import java.util.*; // Map\nimport org.example.*; // Dialog"; string(importBlock) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, importBlock)
+	}
+
+	ima.NestedImports = true
+	fixed, err := ima.FixImports([]byte(source), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `package main;
+
+import java.util.Map.*; // Entry
+import org.example.Dialog.*; // Builder
+
+class Main {
+    Entry<String, Builder> entry;
+    Builder builder = new Builder();
+}
+`
+	if string(fixed) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, fixed)
+	}
+
+	// A nested class is not shortened if the name is already used for another type
+	clashing := strings.Replace(source, "class Main {", "class Main {\n    class Entry {}", 1)
+	importBlock, err = ima.ImportBlock([]byte(clashing), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "import java.util.*; // Map\nimport org.example.Dialog.*; // Builder"; string(importBlock) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, importBlock)
+	}
+
+	// Kotlin has its own Map.Entry
+	importBlock, err = ima.ForLanguage(Kotlin).ImportBlock([]byte("val entry: Map.Entry<String, Int>? = null\n"), false)
+	if err != nil || len(importBlock) != 0 {
+		t.Fatalf("Expected no imports for Map.Entry in Kotlin, got %q (%v)\n", importBlock, err)
+	}
+}
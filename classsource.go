@@ -0,0 +1,230 @@
+package autoimport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ClassSource is a place where classes can be found, like a directory of .jar files, a single archive,
+// a directory of .class files or a project source tree. Classes sends the classes that are found to the
+// given chan, with their fully qualified class paths and what else is known about them, like the archive
+// and the module. Classes should not close the chan. The ClassInfo of a nested class should also have
+// the Nested name set, like "Map.Entry".
+type ClassSource interface {
+	Classes(found chan<- ClassInfo) error
+}
+
+// matcherSource is implemented by the built-in class sources, which use the cache of the ImportMatcher,
+// and collect the errors for the archives that can not be read. The error for the class source itself,
+// like an archive that can not be read, is also returned, while archives that are found within
+// directories are skipped if they can not be read.
+type matcherSource interface {
+	findClasses(ima *ImportMatcher, found chan ClassInfo) error
+}
+
+// JARDirSource is a directory that is searched recursively for .jar files, .jmod files, "lib/src.zip" files,
+// "lib/modules" jimage files and build output directories of .class files, like a JDK installation or a project
+type JARDirSource struct {
+	Path string
+}
+
+// ArchiveSource is a single .jar, .war, .jmod, src.zip or jimage file.
+// Nested .jar files within .jar and .war files are also searched.
+type ArchiveSource struct {
+	Path string
+}
+
+// ClassDirSource is a directory of .class files, like "build/classes/java/main" or "target/classes",
+// where the directories within it are the packages
+type ClassDirSource struct {
+	Path string
+}
+
+// JMODSource is a .jmod file, or a directory of .jmod files, like the "jmods" directory of a JDK
+type JMODSource struct {
+	Path string
+}
+
+// SourceTreeSource is a tree of .java and .kt files, like "src/main/java", where the top-level types that are
+// declared in each file are found. The classes are marked as project classes.
+type SourceTreeSource struct {
+	Path string
+}
+
+// StaticSource is a fixed list of classes, for instance from a list of class names that has been generated elsewhere
+type StaticSource []ClassInfo
+
+// String returns the path of the directory
+func (source JARDirSource) String() string {
+	return source.Path
+}
+
+// String returns the path of the archive
+func (source ArchiveSource) String() string {
+	return source.Path
+}
+
+// String returns the path of the directory
+func (source ClassDirSource) String() string {
+	return source.Path
+}
+
+// String returns the path of the .jmod file or directory
+func (source JMODSource) String() string {
+	return source.Path
+}
+
+// String returns the path of the source tree
+func (source SourceTreeSource) String() string {
+	return source.Path
+}
+
+func (source JARDirSource) findClasses(ima *ImportMatcher, found chan ClassInfo) error {
+	ima.findClassesInJarOrSrc(source.Path, found)
+	return nil
+}
+
+func (source ArchiveSource) findClasses(ima *ImportMatcher, found chan ClassInfo) error {
+	read := ima.readJAR
+	switch {
+	case filepath.Ext(source.Path) == ".jmod":
+		read = ima.readJMOD
+	case filepath.Base(source.Path) == "src.zip":
+		read = ima.readSOURCE
+	case filepath.Base(source.Path) == "modules":
+		read = ima.readJImage
+	}
+	return ima.readCached(source.Path, read, found)
+}
+
+func (source ClassDirSource) findClasses(ima *ImportMatcher, found chan ClassInfo) error {
+	if err := readClassDir(source.Path, found); err != nil {
+		return ima.addArchiveError(source.Path, err)
+	}
+	return nil
+}
+
+func (source JMODSource) findClasses(ima *ImportMatcher, found chan ClassInfo) error {
+	err := filepath.Walk(source.Path, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && filepath.Ext(path) == ".jmod" {
+			ima.readCached(path, ima.readJMOD, found)
+		}
+		return nil
+	})
+	if err != nil {
+		return ima.addArchiveError(source.Path, err)
+	}
+	return nil
+}
+
+func (source SourceTreeSource) findClasses(ima *ImportMatcher, found chan ClassInfo) error {
+	ima.findClassesInSourceTree(source.Path, found)
+	return nil
+}
+
+func (source StaticSource) findClasses(ima *ImportMatcher, found chan ClassInfo) error {
+	for _, info := range source {
+		found <- info
+	}
+	return nil
+}
+
+// Classes sends the classes in the archives in the directory to the found chan
+func (source JARDirSource) Classes(found chan<- ClassInfo) error {
+	return sourceClasses(source, found)
+}
+
+// Classes sends the classes in the archive to the found chan
+func (source ArchiveSource) Classes(found chan<- ClassInfo) error {
+	return sourceClasses(source, found)
+}
+
+// Classes sends the classes in the directory to the found chan
+func (source ClassDirSource) Classes(found chan<- ClassInfo) error {
+	return sourceClasses(source, found)
+}
+
+// Classes sends the classes in the .jmod files to the found chan
+func (source JMODSource) Classes(found chan<- ClassInfo) error {
+	return sourceClasses(source, found)
+}
+
+// Classes sends the top-level types that are declared in the source tree to the found chan
+func (source SourceTreeSource) Classes(found chan<- ClassInfo) error {
+	return sourceClasses(source, found)
+}
+
+// Classes sends the classes in the list to the found chan
+func (source StaticSource) Classes(found chan<- ClassInfo) error {
+	return sourceClasses(source, found)
+}
+
+// sourceClasses sends the classes of a built-in class source to the found chan, without using a cache,
+// and returns the error for the class source, or else the first error for an archive that could not be read
+func sourceClasses(source matcherSource, found chan<- ClassInfo) error {
+	ima := &ImportMatcher{projectImports: make(map[string]int)}
+	scanned := make(chan ClassInfo)
+	var err error
+	go func() {
+		err = source.findClasses(ima, scanned)
+		close(scanned)
+	}()
+	for info := range scanned {
+		found <- info
+	}
+	if err == nil && len(ima.archiveErrors) > 0 {
+		err = ima.archiveErrors[0]
+	}
+	return err
+}
+
+// findClasses sends the classes of the given class source to the found chan, and returns an error if the
+// class source fails, as an *ArchiveError for the built-in class sources, or else as a *SourceError.
+// The built-in class sources use the cache.
+func (ima *ImportMatcher) findClasses(source ClassSource, found chan ClassInfo) error {
+	if source, ok := source.(matcherSource); ok {
+		return source.findClasses(ima, found)
+	}
+	if err := source.Classes(found); err != nil {
+		return &SourceError{Source: source, Err: err}
+	}
+	return nil
+}
+
+// isClassDir checks if the given directory is a build output directory of .class files,
+// like "target/classes", "build/classes/java/main", "build/classes/kotlin/main" or "WEB-INF/classes"
+func isClassDir(path string) bool {
+	path = filepath.Clean(path)
+	parent := filepath.Dir(path)
+	if filepath.Base(path) == "classes" {
+		return filepath.Base(parent) == "target" || filepath.Base(parent) == "WEB-INF"
+	}
+	grandparent := filepath.Dir(parent)
+	return filepath.Base(grandparent) == "classes" && filepath.Base(filepath.Dir(grandparent)) == "build"
+}
+
+// readClassDir sends the classes in the given directory of .class files to the found chan.
+// The path of each .class file within the directory is used as if it was an entry in an archive.
+func readClassDir(dirPath string, found chan ClassInfo) error {
+	return filepath.Walk(dirPath, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() || !strings.HasSuffix(path, ".class") {
+			return nil
+		}
+		entryName, err := filepath.Rel(dirPath, path)
+		if err != nil {
+			return nil
+		}
+		if info, ok := classEntryInfo(filepath.ToSlash(entryName), func() ([]byte, error) { return os.ReadFile(path) }); ok {
+			info.Archive = dirPath
+			found <- info
+		}
+		return nil
+	})
+}
@@ -0,0 +1,127 @@
+package autoimport
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// mirrorSource is a ClassSource that reads class names from a snapshot of an artifact mirror
+type mirrorSource struct {
+	classPaths []string
+	err        error
+}
+
+func (source mirrorSource) Classes(found chan<- ClassInfo) error {
+	for _, classPath := range source.classPaths {
+		found <- ClassInfo{Path: classPath, Archive: "mirror"}
+	}
+	return source.err
+}
+
+func (source mirrorSource) String() string {
+	return "mirror"
+}
+
+func TestClassSources(t *testing.T) {
+	jdkPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(jdkPath, "rt.jar"), nil, "java/util/ArrayList.class")
+
+	classDir := filepath.Join(t.TempDir(), "build", "classes", "java", "main")
+	if err := os.MkdirAll(filepath.Join(classDir, "com", "example"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string][]byte{
+		"Service.class":        testClassFile("com.example.Service", accPublic, 0, nil, nil),
+		"Service$Config.class": testClassFile("com.example.Service$Config", accPublic, 0, nil, nil, innerClass{name: "com.example.Service$Config", outer: "com.example.Service", access: accPublic | accStatic}),
+		"Hidden.class":         testClassFile("com.example.Hidden", 0, 0, nil, nil),
+	} {
+		if err := os.WriteFile(filepath.Join(classDir, "com", "example", name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	jmodsPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(jmodsPath, "java.sql.jmod"), jmodMagic, "classes/java/sql/Connection.class")
+
+	ima, err := NewWithOptions(Options{
+		Language: Java,
+		JARPaths: []string{jdkPath},
+		Sources: []ClassSource{
+			ClassDirSource{Path: classDir},
+			JMODSource{Path: jmodsPath},
+			StaticSource{{Path: "org.generated.Listed"}},
+			mirrorSource{classPaths: []string{"com.internal.mirror.Widget"}},
+		},
+		CachePath: filepath.Join(t.TempDir(), "classes.gob"),
+	})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	for className, expected := range map[string]string{
+		"ArrayList":      "java.util.ArrayList",
+		"Service":        "com.example.Service",
+		"Service.Config": "com.example.Service.Config",
+		"Hidden":         "",
+		"Connection":     "java.sql.Connection",
+		"Listed":         "org.generated.Listed",
+		"Widget":         "com.internal.mirror.Widget",
+	} {
+		if classPath := ima.ImportPathExact(className); classPath != expected {
+			t.Errorf("Expected %q for %s, got %q\n", expected, className, classPath)
+		}
+	}
+	if info, _ := ima.Info("com.example.Service"); info.Archive != classDir || info.Kind != ClassType {
+		t.Errorf("Unexpected information about a class in a class directory: %+v\n", info)
+	}
+	if module := ima.Module("java.sql.Connection"); module != "java.sql" {
+		t.Errorf("Expected the java.sql module, got %q\n", module)
+	}
+	if len(ima.Sources) != 5 {
+		t.Errorf("Expected the JAR path and the four given sources, got %v\n", ima.Sources)
+	}
+
+	// A class source that fails makes NewWithOptions fail
+	mirrorErr := errors.New("the snapshot is incomplete")
+	_, err = NewWithOptions(Options{Language: Java, JARPaths: []string{jdkPath}, Sources: []ClassSource{mirrorSource{err: mirrorErr}}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	var sourceError *SourceError
+	if !errors.As(err, &sourceError) || sourceError.Source.(mirrorSource).err != mirrorErr || !errors.Is(err, mirrorErr) {
+		t.Fatalf("Expected a *SourceError for the mirror, got %v\n", err)
+	}
+	if expected := "could not find the classes in mirror: the snapshot is incomplete"; err.Error() != expected {
+		t.Errorf("Expected %q, got %q\n", expected, err.Error())
+	}
+}
+
+func TestClassSourceClasses(t *testing.T) {
+	jarPath := filepath.Join(t.TempDir(), "lib.jar")
+	writeTestArchive(t, jarPath, nil, "org/lib/Alpha.class", "org/lib/Beta.class", "org/lib/Alpha$1.class")
+
+	found := make(chan ClassInfo)
+	var classPaths []string
+	done := make(chan error, 1)
+	go func() {
+		done <- ArchiveSource{Path: jarPath}.Classes(found)
+		close(found)
+	}()
+	for info := range found {
+		if info.Archive != jarPath {
+			t.Errorf("Expected the archive to be %s, got %s\n", jarPath, info.Archive)
+		}
+		classPaths = append(classPaths, info.Path)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(classPaths)
+	if len(classPaths) != 2 || classPaths[0] != "org.lib.Alpha" || classPaths[1] != "org.lib.Beta" {
+		t.Fatalf("Expected Alpha and Beta, got %v\n", classPaths)
+	}
+
+	err := ArchiveSource{Path: filepath.Join(t.TempDir(), "missing.jar")}.Classes(make(chan ClassInfo))
+	var archiveError *ArchiveError
+	if !errors.As(err, &archiveError) {
+		t.Fatalf("Expected an *ArchiveError for a missing archive, got %v\n", err)
+	}
+}
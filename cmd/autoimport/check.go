@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alexflint/go-arg"
+	"github.com/xyproto/autoimport"
+)
+
+// Exit codes for the check subcommand
+const (
+	checkOK       = 0 // no problems were found
+	checkProblems = 1 // problems with the imports were found
+	checkError    = 2 // some files could not be checked, or the arguments are wrong
+)
+
+// CheckArgs defines the possible command line arguments for "autoimport check"
+type CheckArgs struct {
+	Paths        []string `arg:"positional,required" placeholder:"PATH" help:"the files and directories to check"`
+	Dependencies bool     `arg:"-m,--dependencies" help:"also search the local Maven repository and Gradle cache"`
+	Project      bool     `arg:"--project" help:"also search the dependencies declared in the nearest pom.xml or build.gradle(.kts)"`
+	RebuildCache bool     `arg:"--rebuild-cache" help:"scan all archives again instead of using the class index cache"`
+	Jobs         int      `arg:"--jobs" help:"the number of files to check in parallel (default: the number of CPUs)"`
+	Socket       string   `arg:"--socket" help:"the Unix domain socket of a running daemon (see autoimport serve)"`
+	NoDaemon     bool     `arg:"--no-daemon" help:"index the classes in this process, even if a daemon is running"`
+	JSON         bool     `arg:"--json" help:"output one JSON record per problem"`
+	Release      int      `arg:"--release" help:"the target Java release, like 17, for choosing the classes in multi-release jars (default: all releases)"`
+	Layout       string   `arg:"--layout" help:"the import layout: lexicographic, google, intellij, ktlint or comma separated patterns (default: from the nearest .autoimport file)"`
+}
+
+// Description is shown at the top of the help output for "autoimport check"
+func (CheckArgs) Description() string {
+	return "Check the imports of Java and Kotlin files, without changing them.\n" +
+		"Outputs one line per missing, unused or unsorted import.\n" +
+		"Exits with 0 if there are no problems, 1 if there are problems and 2 if there are errors.\n"
+}
+
+// checkMain runs the check subcommand with the given arguments, and returns the exit code
+func checkMain(arguments []string) int {
+	var checkArgs CheckArgs
+	p, err := arg.NewParser(arg.Config{Program: "autoimport check"}, &checkArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return checkError
+	}
+	if err := p.Parse(arguments); err != nil {
+		if errors.Is(err, arg.ErrHelp) {
+			p.WriteHelp(os.Stdout)
+			return checkOK
+		}
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return checkError
+	}
+
+	if checkArgs.Layout != "" {
+		if _, err := autoimport.ParseImportLayout(checkArgs.Layout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return checkError
+		}
+	}
+
+	args := &Args{
+		Dependencies: checkArgs.Dependencies,
+		Project:      checkArgs.Project,
+		RebuildCache: checkArgs.RebuildCache,
+		Jobs:         checkArgs.Jobs,
+		Socket:       checkArgs.Socket,
+		NoDaemon:     checkArgs.NoDaemon,
+		JSON:         checkArgs.JSON,
+		Layout:       checkArgs.Layout,
+		Release:      checkArgs.Release,
+	}
+	fx := newFixer(args)
+	fx.check = true
+	problems, ok := fx.fixFiles(checkArgs.Paths)
+	switch {
+	case !ok:
+		return checkError
+	case problems:
+		return checkProblems
+	}
+	return checkOK
+}
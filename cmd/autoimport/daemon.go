@@ -0,0 +1,400 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/alexflint/go-arg"
+	"github.com/xyproto/autoimport"
+	"github.com/xyproto/env/v2"
+)
+
+// daemonDialTimeout is how long the CLI waits when connecting to a running daemon
+const daemonDialTimeout = time.Second
+
+// ServeArgs defines the possible command line arguments for "autoimport serve"
+type ServeArgs struct {
+	Socket       string `arg:"--socket" help:"the path of the Unix domain socket (default: $AUTOIMPORT_SOCKET, or autoimport.sock in $XDG_RUNTIME_DIR)"`
+	Dependencies bool   `arg:"-m,--dependencies" help:"index the local Maven repository and Gradle cache when starting"`
+}
+
+// Description is shown at the top of the help output for "autoimport serve"
+func (ServeArgs) Description() string {
+	return "Run a daemon that keeps the class index in memory, and answers requests over a Unix domain socket.\n" +
+		"The autoimport command uses the daemon when it is running.\n"
+}
+
+// defaultSocketPath returns the path of the Unix domain socket that the daemon listens to
+func defaultSocketPath() string {
+	if socketPath := env.Str("AUTOIMPORT_SOCKET"); socketPath != "" {
+		return socketPath
+	}
+	if runtimeDir := env.Str("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		return filepath.Join(runtimeDir, "autoimport.sock")
+	}
+	return filepath.Join(privateSocketDir(), "autoimport.sock")
+}
+
+// privateSocketDir returns the directory for the socket when $XDG_RUNTIME_DIR is not set.
+// The directory is only accessible by the current user, so that other users can not
+// create a socket in its place before the daemon is started.
+func privateSocketDir() string {
+	return filepath.Join(os.TempDir(), fmt.Sprintf("autoimport-%d", os.Getuid()))
+}
+
+// makePrivateSocketDir creates the directory returned by privateSocketDir, if it does not exist,
+// and checks that it is owned by the current user and not accessible by others
+func makePrivateSocketDir() error {
+	dir := privateSocketDir()
+	if err := os.Mkdir(dir, 0o700); err != nil && !errors.Is(err, os.ErrExist) {
+		return err
+	}
+	fi, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !fi.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	return checkPrivate(dir, fi)
+}
+
+// checkSocket returns an error if the given path is not a socket that was created by the current user.
+// Requests contain source code, so they are never sent to a daemon that is run by another user.
+func checkSocket(socketPath string) error {
+	fi, err := os.Lstat(socketPath)
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s is not a socket", socketPath)
+	}
+	return checkPrivate(socketPath, fi)
+}
+
+// daemonRequest is a request to the daemon. Each request and response is a JSON object on a single line.
+type daemonRequest struct {
+	Method       string `json:"method"` // "ping", "lookup", "importBlock", "fix" or "check"
+	Java         bool   `json:"java,omitempty"`
+	Dependencies bool   `json:"dependencies,omitempty"`
+	ProjectFile  string `json:"projectFile,omitempty"` // an absolute path, for finding the project dependencies
+	NoGlob       bool   `json:"noGlob,omitempty"`
+	Nested       bool   `json:"nested,omitempty"`  // import nested classes instead of their outer classes
+	Release      int    `json:"release,omitempty"` // the target Java release for multi-release jars
+	Name         string `json:"name,omitempty"`    // the (start of the) class name to look up
+	Exact        bool   `json:"exact,omitempty"`
+	Shortest     bool   `json:"shortest,omitempty"`
+	Filename     string `json:"filename,omitempty"`
+	Source       string `json:"source,omitempty"`
+	Layout       string `json:"layout,omitempty"` // the import layout, as a list of patterns
+
+	Preferences *autoimport.Preferences `json:"preferences,omitempty"` // which classes to import for ambiguous names
+}
+
+// setLayout sets the import layout of the request, if there is one
+func (req *daemonRequest) setLayout(layout *autoimport.ImportLayout) {
+	if layout != nil {
+		req.Layout = layout.String()
+	}
+}
+
+// daemonResponse is a response from the daemon
+type daemonResponse struct {
+	Matches   []autoimport.Match   `json:"matches,omitempty"` // the found classes, for lookup and importBlock
+	Source    string               `json:"source,omitempty"`  // the import block, or the fixed source code
+	Problems  []autoimport.Problem `json:"problems,omitempty"`
+	Error     string               `json:"error,omitempty"`
+	ErrorLine int                  `json:"errorLine,omitempty"` // the line number, if the error is a parse error
+}
+
+// daemonKey identifies an ImportMatcher in the daemon
+type daemonKey struct {
+	dependencies bool
+	buildFile    string
+	release      int
+}
+
+// daemon holds one warm ImportMatcher per combination of dependencies, project and target release
+type daemon struct {
+	newMatcher func(opts autoimport.Options) (*autoimport.ImportMatcher, bool, error)
+	mut        sync.Mutex
+	matchers   map[daemonKey]*workspaceMatcher
+}
+
+// newDaemon creates a new daemon that uses the given function for creating ImportMatchers
+func newDaemon(newMatcher func(opts autoimport.Options) (*autoimport.ImportMatcher, bool, error)) *daemon {
+	return &daemon{newMatcher: newMatcher, matchers: make(map[daemonKey]*workspaceMatcher)}
+}
+
+// matcher returns an ImportMatcher for the language and options of the given request
+func (d *daemon) matcher(req daemonRequest) (*autoimport.ImportMatcher, error) {
+	key := daemonKey{dependencies: req.Dependencies, release: req.Release}
+	if req.ProjectFile != "" {
+		key.buildFile = autoimport.FindBuildFile(req.ProjectFile)
+	}
+	d.mut.Lock()
+	wm, ok := d.matchers[key]
+	if !ok {
+		wm = &workspaceMatcher{}
+		d.matchers[key] = wm
+	}
+	d.mut.Unlock()
+	wm.once.Do(func() {
+		wm.ima, wm.kotlinOK, wm.err = d.newMatcher(autoimport.Options{
+			Language:     autoimport.Kotlin,
+			Dependencies: req.Dependencies,
+			ProjectFile:  req.ProjectFile,
+			Release:      req.Release,
+		})
+		if wm.err == nil {
+			wm.modTime = wm.ima.SourcesModTime()
+		}
+	})
+	if wm.err != nil {
+		return nil, wm.err
+	}
+	if !req.Java && !wm.kotlinOK {
+		return nil, autoimport.ErrNoKotlin
+	}
+	language := autoimport.Kotlin
+	if req.Java {
+		language = autoimport.Java
+	}
+	ima := d.refresh(wm).ForLanguage(language)
+	ima.DeGlob = req.NoGlob
+	ima.NestedImports = req.Nested
+	ima.Preferences = req.Preferences
+	if req.Layout != "" {
+		layout, err := autoimport.ParseImportLayout(req.Layout)
+		if err != nil {
+			return nil, err
+		}
+		ima.Layout = layout
+	}
+	return ima, nil
+}
+
+// refresh searches the project source trees of the given ImportMatcher again if they have been modified
+// since they were last searched, so that classes that are added to or removed from the project are found
+// without restarting the daemon. The classes in the archives are not indexed again.
+// The source trees are searched without holding the lock, so that other requests are not kept waiting.
+func (d *daemon) refresh(wm *workspaceMatcher) *autoimport.ImportMatcher {
+	d.mut.Lock()
+	ima, lastModTime := wm.ima, wm.modTime
+	d.mut.Unlock()
+	if len(ima.SourcePaths) == 0 {
+		return ima
+	}
+	modTime := ima.SourcesModTime()
+	if !modTime.After(lastModTime) {
+		return ima
+	}
+	reloaded := ima.ReloadSources()
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	// Another request may have reloaded the source trees in the meantime
+	if modTime.After(wm.modTime) {
+		wm.ima, wm.modTime = reloaded, modTime
+	}
+	return wm.ima
+}
+
+// handle answers a single request
+func (d *daemon) handle(req daemonRequest) daemonResponse {
+	var resp daemonResponse
+	if req.Method == "ping" {
+		return resp
+	}
+	ima, err := d.matcher(req)
+	if err != nil {
+		resp.Error = err.Error()
+		return resp
+	}
+	switch req.Method {
+	case "lookup":
+		resp.Matches = lookupMatches(ima, req.Name, req.Exact, req.Shortest)
+	case "importBlock":
+		var importBlock []byte
+		importBlock, err = ima.ImportBlock([]byte(req.Source), false)
+		resp.Source = string(importBlock)
+		if err == nil {
+			resp.Matches, err = ima.ImportMatches([]byte(req.Source))
+		}
+	case "fix":
+		var newSource []byte
+		newSource, err = ima.FixImports([]byte(req.Source), false)
+		resp.Source = string(newSource)
+	case "check":
+		resp.Problems, err = ima.CheckImports([]byte(req.Source))
+	default:
+		err = fmt.Errorf("unknown method: %q", req.Method)
+	}
+	if err != nil {
+		resp.Error = err.Error()
+		var parseError *autoimport.ParseError
+		if errors.As(err, &parseError) {
+			resp.Error = parseError.Err.Error()
+			resp.ErrorLine = parseError.Line
+		}
+	}
+	return resp
+}
+
+// serveConn answers requests on the given connection until it is closed
+func (d *daemon) serveConn(conn net.Conn) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	enc := json.NewEncoder(conn)
+	for {
+		var req daemonRequest
+		if err := dec.Decode(&req); err != nil {
+			return
+		}
+		if err := enc.Encode(d.handle(req)); err != nil {
+			return
+		}
+	}
+}
+
+// serve accepts connections until the listener is closed
+func (d *daemon) serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go d.serveConn(conn)
+	}
+}
+
+// serveMain runs the serve subcommand with the given arguments, and returns the exit code
+func serveMain(arguments []string) int {
+	var serveArgs ServeArgs
+	p, err := arg.NewParser(arg.Config{Program: "autoimport serve"}, &serveArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := p.Parse(arguments); err != nil {
+		if errors.Is(err, arg.ErrHelp) {
+			p.WriteHelp(os.Stdout)
+			return 0
+		}
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	socketPath := serveArgs.Socket
+	if socketPath == "" {
+		socketPath = defaultSocketPath()
+		if filepath.Dir(socketPath) == privateSocketDir() {
+			if err := makePrivateSocketDir(); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				return 1
+			}
+		}
+	}
+	if (&daemonClient{socketPath: socketPath}).ping() == nil {
+		fmt.Fprintf(os.Stderr, "a daemon is already listening on %s\n", socketPath)
+		return 1
+	}
+	if err := removeStaleSocket(socketPath); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	listener, err := listenSocket(socketPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		listener.Close()
+	}()
+
+	d := newDaemon(newMatcher)
+	// Index the classes in the background, so that they are ready for the first request
+	go d.matcher(daemonRequest{Java: true, Dependencies: serveArgs.Dependencies})
+
+	fmt.Fprintf(os.Stderr, "listening on %s\n", socketPath)
+	if err := d.serve(listener); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// removeStaleSocket removes the socket file of a daemon that did not exit cleanly.
+// Files that are not sockets are never removed, in case --socket points to the wrong file.
+func removeStaleSocket(socketPath string) error {
+	fi, err := os.Lstat(socketPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&os.ModeSocket == 0 {
+		return fmt.Errorf("%s already exists, and is not a socket", socketPath)
+	}
+	return os.Remove(socketPath)
+}
+
+// daemonClient sends requests to a running daemon. A new connection is used for each request,
+// so that a daemonClient can be used from several goroutines at the same time.
+type daemonClient struct {
+	socketPath string
+}
+
+// call sends a request to the daemon and returns the response.
+// Errors from the daemon are returned as errors, with the same types as in the autoimport package
+// where possible. The given filename is used for parse errors. Sockets that are not created by the
+// current user are not connected to.
+func (c *daemonClient) call(req daemonRequest) (daemonResponse, error) {
+	var resp daemonResponse
+	if err := checkSocket(c.socketPath); err != nil {
+		return resp, err
+	}
+	conn, err := net.DialTimeout("unix", c.socketPath, daemonDialTimeout)
+	if err != nil {
+		return resp, err
+	}
+	defer conn.Close()
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return resp, err
+	}
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return resp, err
+	}
+	switch {
+	case resp.Error == "":
+		return resp, nil
+	case resp.ErrorLine > 0:
+		return resp, &autoimport.ParseError{Filename: req.Filename, Line: resp.ErrorLine, Err: errors.New(resp.Error)}
+	case resp.Error == autoimport.ErrNoJava.Error():
+		return resp, autoimport.ErrNoJava
+	case resp.Error == autoimport.ErrNoKotlin.Error():
+		return resp, autoimport.ErrNoKotlin
+	}
+	return resp, errors.New(resp.Error)
+}
+
+// ping checks if the daemon is running
+func (c *daemonClient) ping() error {
+	_, err := c.call(daemonRequest{Method: "ping"})
+	return err
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/xyproto/autoimport"
+)
+
+func TestDaemon(t *testing.T) {
+	// Unix domain socket paths have a short maximum length, so t.TempDir() can not be used
+	dir, err := os.MkdirTemp("", "autoimport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "autoimport.sock")
+	listener, err := listenSocket(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	newTestMatcher := testMatcher(t)
+	d := newDaemon(func(opts autoimport.Options) (*autoimport.ImportMatcher, bool, error) {
+		return newTestMatcher(daemonKey{})
+	})
+	done := make(chan error)
+	go func() {
+		done <- d.serve(listener)
+	}()
+
+	client := &daemonClient{socketPath: socketPath}
+	if err := client.ping(); err != nil {
+		t.Fatalf("Could not ping the daemon: %v\n", err)
+	}
+
+	resp, err := client.call(daemonRequest{Method: "lookup", Java: true, Name: "Hash"})
+	if err != nil || len(resp.Matches) != 1 || resp.Matches[0].Name != "HashMap" || resp.Matches[0].Import != "java.util.*" {
+		t.Errorf("Unexpected lookup response: %+v, %v\n", resp, err)
+	}
+
+	source := "package main;\n\nclass Main {\n    ArrayList<String> a;\n}\n"
+	resp, err = client.call(daemonRequest{Method: "importBlock", Java: true, Source: source})
+	if err != nil || resp.Source != "import java.util.*; // ArrayList" || len(resp.Matches) != 1 || resp.Matches[0].Line != 4 {
+		t.Errorf("Unexpected import block: %+v, %v\n", resp, err)
+	}
+
+	resp, err = client.call(daemonRequest{Method: "fix", Java: true, NoGlob: true, Source: "package main;\n\nimport java.util.*;\n\nclass Main {\n    ArrayList<String> a;\n}\n"})
+	if expected := "package main;\n\nimport java.util.ArrayList;\n\nclass Main {\n    ArrayList<String> a;\n}\n"; err != nil || resp.Source != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s (%v)\n", expected, resp.Source, err)
+	}
+
+	resp, err = client.call(daemonRequest{Method: "fix", Java: true, NoGlob: true, Layout: "google", Source: "package main;\n\nimport java.util.*;\nimport static java.util.Collections.emptyList;\n\nclass Main {\n    ArrayList<String> a;\n}\n"})
+	if expected := "package main;\n\nimport static java.util.Collections.emptyList;\n\nimport java.util.ArrayList;\n\nclass Main {\n    ArrayList<String> a;\n}\n"; err != nil || resp.Source != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s (%v)\n", expected, resp.Source, err)
+	}
+	if _, err = client.call(daemonRequest{Method: "fix", Java: true, Layout: "java.**", Source: source}); err == nil {
+		t.Errorf("Expected an error for an invalid import layout\n")
+	}
+
+	resp, err = client.call(daemonRequest{Method: "check", Java: true, Source: source})
+	if err != nil || len(resp.Problems) != 1 || resp.Problems[0].Kind != autoimport.MissingImport || resp.Problems[0].Line != 4 {
+		t.Errorf("Unexpected problems: %+v, %v\n", resp, err)
+	}
+
+	_, err = client.call(daemonRequest{Method: "fix", Java: true, Filename: "Main.java", Source: "class Main { String s = \"unterminated; }"})
+	var parseError *autoimport.ParseError
+	if !errors.As(err, &parseError) || parseError.Filename != "Main.java" || parseError.Line != 1 {
+		t.Errorf("Expected a parse error for Main.java, got %v\n", err)
+	}
+
+	listener.Close()
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error when closing the daemon: %v\n", err)
+	}
+	if err := client.ping(); err == nil {
+		t.Fatalf("Expected an error when the daemon is not running\n")
+	}
+}
+
+func TestDaemonProjectRefresh(t *testing.T) {
+	projectPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectPath, "pom.xml"), []byte("<project></project>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	packagePath := filepath.Join(projectPath, "src", "main", "java", "com", "example")
+	if err := os.MkdirAll(packagePath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(packagePath, "Service.java"), []byte("package com.example;\n\npublic class Service {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	libPath, cachePath := t.TempDir(), filepath.Join(t.TempDir(), "classes.gob")
+	indexed := 0
+	d := newDaemon(func(opts autoimport.Options) (*autoimport.ImportMatcher, bool, error) {
+		indexed++
+		opts.JARPaths = []string{libPath}
+		opts.CachePath = cachePath
+		return newMatcher(opts)
+	})
+	lookup := func(name string) []autoimport.Match {
+		t.Helper()
+		resp := d.handle(daemonRequest{Method: "lookup", Java: true, Exact: true, Name: name, ProjectFile: filepath.Join(packagePath, "Main.java")})
+		if resp.Error != "" {
+			t.Fatal(resp.Error)
+		}
+		return resp.Matches
+	}
+	if matches := lookup("Service"); len(matches) != 1 {
+		t.Fatalf("Expected the project class, got %+v\n", matches)
+	}
+	if matches := lookup("Client"); len(matches) != 0 {
+		t.Fatalf("Expected no Client class yet, got %+v\n", matches)
+	}
+
+	// A class that is added to the project is found by the next request
+	clientPath := filepath.Join(packagePath, "Client.java")
+	if err := os.WriteFile(clientPath, []byte("package com.example;\n\npublic class Client {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(clientPath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if matches := lookup("Client"); len(matches) != 1 || matches[0].Class != "com.example.Client" {
+		t.Fatalf("Expected the added project class, got %+v\n", matches)
+	}
+	if indexed != 1 {
+		t.Fatalf("Expected the classes to be indexed once, and the source tree to be searched again, got %d\n", indexed)
+	}
+}
+
+func TestDaemonSocket(t *testing.T) {
+	// A short directory name is used, since the path of a socket can not be long
+	dir, err := os.MkdirTemp("", "autoimport")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A file that is not a socket is never removed
+	notASocket := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(notASocket, []byte("keep me"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if exitCode := serveMain([]string{"--socket", notASocket}); exitCode != 1 {
+		t.Fatalf("Expected serve to fail for a file that is not a socket, got exit code %d\n", exitCode)
+	}
+	if data, err := os.ReadFile(notASocket); err != nil || string(data) != "keep me" {
+		t.Fatalf("Expected the file to be kept, got %q (%v)\n", data, err)
+	}
+
+	socketPath := filepath.Join(dir, "autoimport.sock")
+	listener, err := listenSocket(socketPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" {
+		if fi, err := os.Stat(socketPath); err != nil || fi.Mode().Perm() != 0o600 {
+			t.Errorf("Expected only the current user to be able to connect, got %v (%v)\n", fi.Mode(), err)
+		}
+	}
+	if err := checkSocket(socketPath); err != nil {
+		t.Errorf("Expected the socket to be accepted: %v\n", err)
+	}
+	if runtime.GOOS != "windows" {
+		// A socket that other users can connect to could have been created by another user
+		if err := os.Chmod(socketPath, 0o666); err != nil {
+			t.Fatal(err)
+		}
+		if err := (&daemonClient{socketPath: socketPath}).ping(); err == nil {
+			t.Error("Expected a socket that is accessible by other users to be rejected\n")
+		}
+	}
+	if err := checkSocket(notASocket); err == nil {
+		t.Error("Expected a file that is not a socket to be rejected\n")
+	}
+	// Leave the socket file behind, like a daemon that did not exit cleanly
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	if err := removeStaleSocket(socketPath); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Lstat(socketPath); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("Expected the stale socket to be removed, got %v\n", err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	oldData := []byte("package main;\n\nimport java.util.*; // ArrayList\n\nclass A {\n    ArrayList<String> a;\n    HashMap<String, String> m;\n}\n")
+	newData := []byte("package main;\n\nimport java.util.*; // ArrayList, HashMap\n\nclass A {\n    ArrayList<String> a;\n    HashMap<String, String> m;\n}\n")
+	expected := `--- a/A.java
++++ b/A.java
+@@ -1,6 +1,6 @@
+ package main;
+ 
+-import java.util.*; // ArrayList
++import java.util.*; // ArrayList, HashMap
+ 
+ class A {
+     ArrayList<String> a;
+`
+	if got := unifiedDiff("A.java", oldData, newData); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+	if got := unifiedDiff("A.java", oldData, oldData); got != "" {
+		t.Errorf("Expected no diff for identical data, got:\n%s", got)
+	}
+}
+
+func TestUnifiedDiffInsertion(t *testing.T) {
+	oldData := []byte("package main;\n\nclass A {}\n")
+	newData := []byte("package main;\n\nimport java.util.*;\n\nclass A {}\n")
+	expected := `--- a/A.java
++++ b/A.java
+@@ -1,3 +1,5 @@
+ package main;
+ 
++import java.util.*;
++
+ class A {}
+`
+	if got := unifiedDiff("A.java", oldData, newData); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestUnifiedDiffNewlineAtEndOfFile(t *testing.T) {
+	oldData := []byte("package main;\n\nclass A {}")
+	newData := []byte("package main;\n\nclass A {}\n")
+	expected := `--- a/A.java
++++ b/A.java
+@@ -1,3 +1,3 @@
+ package main;
+ 
+-class A {}
+\ No newline at end of file
++class A {}
+`
+	if got := unifiedDiff("A.java", oldData, newData); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestDiffLinesLargeFile(t *testing.T) {
+	// Only the changed lines in the middle of a large file should be compared line by line
+	body := strings.Repeat("    int x = 1;\n", 100000)
+	oldData := []byte("package main;\n\nimport java.util.List;\n\nclass A {\n" + body + "}\n")
+	newData := []byte("package main;\n\nimport java.util.ArrayList;\nimport java.util.List;\n\nclass A {\n" + body + "}\n")
+	ops := diffLines(splitLines(oldData), splitLines(newData))
+	var changes []string
+	for _, op := range ops {
+		if op.kind != ' ' {
+			changes = append(changes, string(op.kind)+op.line)
+		}
+	}
+	if len(ops) != 100007 || len(changes) != 1 || changes[0] != "+import java.util.ArrayList;" {
+		t.Fatalf("Expected one added line out of 100007, got %d lines and the changes %v", len(ops), changes)
+	}
+}
@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+
+	"github.com/xyproto/autoimport"
+)
+
+// matcherKey identifies an ImportMatcher that can be shared by several files
+type matcherKey struct {
+	language  autoimport.Language
+	buildFile string
+}
+
+// fixResult is the result of fixing the imports of a single file
+type fixResult struct {
+	data     []byte               // the original contents of the file
+	newData  []byte               // the contents with fixed imports
+	problems []autoimport.Problem // the problems that are found in check mode
+	err      error
+	done     chan struct{} // closed when the file has been processed
+}
+
+// fixer fixes or checks the imports of source files, sharing one class index for all files that
+// belong to the same project, and processing several files in parallel
+type fixer struct {
+	args     *Args
+	check    bool          // only check the imports, without fixing them
+	daemon   *daemonClient // the running daemon, if any
+	matchers map[matcherKey]*autoimport.ImportMatcher
+	errs     map[matcherKey]error // errors from creating an ImportMatcher
+	input    *bufio.Reader        // where the answers are read from, with --interactive
+	prompt   io.Writer            // where the questions are written to, with --interactive
+}
+
+// newFixer creates a fixer for the given command line arguments
+func newFixer(args *Args) *fixer {
+	return &fixer{
+		args:     args,
+		daemon:   args.daemon(),
+		matchers: make(map[matcherKey]*autoimport.ImportMatcher),
+		errs:     make(map[matcherKey]error),
+		input:    bufio.NewReader(os.Stdin),
+		prompt:   os.Stderr,
+	}
+}
+
+// newMatcher creates an ImportMatcher with the given options. If the options are for Kotlin, but Kotlin
+// can not be found, an ImportMatcher with only the Java classes is created instead, and false is returned.
+func newMatcher(opts autoimport.Options) (*autoimport.ImportMatcher, bool, error) {
+	ima, err := autoimport.NewWithOptions(opts)
+	if opts.Language == autoimport.Kotlin && errors.Is(err, autoimport.ErrNoKotlin) {
+		opts.Language = autoimport.Java
+		ima, err = autoimport.NewWithOptions(opts)
+		return ima, false, err
+	}
+	return ima, true, err
+}
+
+// languageOf returns the language of the given source file, based on the file extension
+func languageOf(filename string) autoimport.Language {
+	if strings.HasSuffix(strings.ToLower(filename), ".java") {
+		return autoimport.Java
+	}
+	return autoimport.Kotlin
+}
+
+// buildFileOf returns the build file of the project that the given file belongs to,
+// if --project is used, or an empty string
+func (fx *fixer) buildFileOf(filename string) string {
+	if !fx.args.Project {
+		return ""
+	}
+	return autoimport.FindBuildFile(filename)
+}
+
+// createMatchers indexes the classes once per project, before the files are processed.
+// The index is created for Kotlin if any of the files are Kotlin files, since that index
+// also contains the Java classes, and then shared between the Java and Kotlin files.
+func (fx *fixer) createMatchers(filenames []string) {
+	var buildFiles []string
+	projectFile := make(map[string]string)
+	hasKotlin := make(map[string]bool)
+	for _, filename := range filenames {
+		buildFile := fx.buildFileOf(filename)
+		if _, ok := projectFile[buildFile]; !ok {
+			buildFiles = append(buildFiles, buildFile)
+			projectFile[buildFile] = filename
+		}
+		if languageOf(filename) == autoimport.Kotlin {
+			hasKotlin[buildFile] = true
+		}
+	}
+	for _, buildFile := range buildFiles {
+		language := autoimport.Java
+		if hasKotlin[buildFile] {
+			language = autoimport.Kotlin
+		}
+		opts := fx.args.options(language, projectFile[buildFile])
+		if fx.args.NoGlob {
+			opts.ImportStyle = autoimport.ExplicitImports
+		}
+		javaKey := matcherKey{autoimport.Java, buildFile}
+		kotlinKey := matcherKey{autoimport.Kotlin, buildFile}
+		ima, kotlinOK, err := newMatcher(opts)
+		if err != nil {
+			fx.errs[javaKey] = err
+			fx.errs[kotlinKey] = err
+			continue
+		}
+		fx.matchers[javaKey] = ima.ForLanguage(autoimport.Java)
+		if language == autoimport.Kotlin && !kotlinOK {
+			// The Java files can still be fixed
+			fx.errs[kotlinKey] = autoimport.ErrNoKotlin
+		} else {
+			fx.matchers[kotlinKey] = ima.ForLanguage(autoimport.Kotlin)
+		}
+	}
+}
+
+// fixFile fixes the imports of the given file, and writes the file if -w is used.
+// In check mode, the problems with the imports are found instead.
+func (fx *fixer) fixFile(filename string, result *fixResult) {
+	defer close(result.done)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		result.err = err
+		return
+	}
+	result.data = data
+	language := languageOf(filename)
+	layout, err := importLayout(fx.args.Layout, filename, language)
+	if err != nil {
+		result.err = err
+		return
+	}
+	prefs, err := autoimport.PreferencesForFile(filename)
+	if err != nil {
+		result.err = err
+		return
+	}
+	if fx.args.Interactive && !fx.check {
+		if prefs, err = fx.resolveAmbiguities(filename, language, data, prefs); err != nil {
+			result.err = err
+			return
+		}
+	}
+	var newData []byte
+	if fx.daemon != nil {
+		method := "fix"
+		if fx.check {
+			method = "check"
+		}
+		req := fx.args.daemonRequest(method, language, filename)
+		req.Source = string(data)
+		req.setLayout(layout)
+		req.Preferences = prefs
+		var resp daemonResponse
+		resp, err = fx.daemon.call(req)
+		newData, result.problems = []byte(resp.Source), resp.Problems
+	} else {
+		key := matcherKey{language, fx.buildFileOf(filename)}
+		if err, ok := fx.errs[key]; ok {
+			result.err = err
+			return
+		}
+		ima := fx.matchers[key]
+		if layout != nil || prefs != nil {
+			// The shared ImportMatcher is not changed, since files may have different settings
+			ima = ima.ForLanguage(language)
+			ima.Layout, ima.Preferences = layout, prefs
+		}
+		if fx.check {
+			result.problems, err = ima.CheckImports(data)
+		} else {
+			newData, err = ima.FixImports(data, fx.args.Verbose)
+		}
+	}
+	if err != nil {
+		var parseError *autoimport.ParseError
+		if errors.As(err, &parseError) && parseError.Filename == "" {
+			parseError.Filename = filename
+		}
+		result.err = err
+		return
+	}
+	if fx.check {
+		return
+	}
+	result.newData = newData
+	if fx.args.Write && !bytes.Equal(data, newData) {
+		fi, err := os.Stat(filename)
+		if err != nil {
+			result.err = err
+			return
+		}
+		result.err = os.WriteFile(filename, newData, fi.Mode().Perm())
+	}
+}
+
+// fileRecord is a JSON record for a file that is fixed, or that could not be processed
+type fileRecord struct {
+	File    string `json:"file"`
+	Changed bool   `json:"changed"`
+	Diff    string `json:"diff,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// problemRecord is a JSON record for a problem that is found in check mode
+type problemRecord struct {
+	File string `json:"file"`
+	autoimport.Problem
+	Message string `json:"message"`
+}
+
+// report outputs the result of fixing the given file, depending on the -l, -d and --json flags,
+// or outputs one line per problem in check mode. Errors are written to stderr, or as JSON records.
+// Returns true if the file has imports that would be changed, or problems in check mode,
+// and false as the second return value if there was an error.
+func (fx *fixer) report(filename string, result *fixResult) (bool, bool) {
+	if result.err != nil {
+		var parseError *autoimport.ParseError
+		var configError *autoimport.ConfigError
+		switch {
+		case fx.args.JSON:
+			printJSON(fileRecord{File: filename, Error: result.err.Error()})
+		case errors.As(result.err, &parseError), errors.As(result.err, &configError):
+			fmt.Fprintln(os.Stderr, result.err) // the filename and line number are already included
+		default:
+			fmt.Fprintf(os.Stderr, "%s: %v\n", filename, result.err)
+		}
+		return false, false
+	}
+	if fx.check {
+		for _, problem := range result.problems {
+			if fx.args.JSON {
+				printJSON(problemRecord{File: filename, Problem: problem, Message: problem.String()})
+			} else {
+				fmt.Printf("%s:%d: %s\n", filename, problem.Line, problem)
+			}
+		}
+		return len(result.problems) > 0, true
+	}
+	changed := !bytes.Equal(result.data, result.newData)
+	if fx.args.JSON {
+		record := fileRecord{File: filename, Changed: changed}
+		if fx.args.Diff {
+			record.Diff = unifiedDiff(filename, result.data, result.newData)
+		}
+		if changed || !fx.args.List {
+			printJSON(record)
+		}
+		return changed, true
+	}
+	if !changed {
+		return false, true
+	}
+	if fx.args.List {
+		fmt.Println(filename)
+	}
+	if fx.args.Diff {
+		fmt.Print(unifiedDiff(filename, result.data, result.newData))
+	}
+	return true, true
+}
+
+// fixFiles fixes the imports of the given files, and of all the source files within the given directories.
+// The files are processed in parallel, but the output is in the same order as the files.
+// Returns true if any of the files have imports that would be changed (or problems, in check mode),
+// and false as the second return value if any of the files could not be processed.
+func (fx *fixer) fixFiles(paths []string) (bool, bool) {
+	filenames, err := autoimport.FindSourceFiles(paths...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return false, false
+	}
+	if fx.daemon == nil {
+		fx.createMatchers(filenames)
+	}
+
+	workers := fx.args.Jobs
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	if fx.args.Interactive {
+		// The questions are asked for one file at a time
+		workers = 1
+	}
+	results := make([]fixResult, len(filenames))
+	for i := range results {
+		results[i].done = make(chan struct{})
+	}
+	indices := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indices {
+				fx.fixFile(filenames[i], &results[i])
+			}
+		}()
+	}
+	go func() {
+		for i := range filenames {
+			indices <- i
+		}
+		close(indices)
+	}()
+
+	changed, ok := false, true
+	for i, filename := range filenames {
+		<-results[i].done
+		fileChanged, fileOK := fx.report(filename, &results[i])
+		changed = changed || fileChanged
+		ok = ok && fileOK
+	}
+	wg.Wait()
+	return changed, ok
+}
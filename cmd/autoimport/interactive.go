@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xyproto/autoimport"
+)
+
+// resolveAmbiguities asks which class to import for each ambiguous class name in the given source file,
+// when --interactive is used. The answers are saved in the .autoimport file of the project, so that they
+// are used for the other files, and the next time. Returns the preferences for the file, including the answers.
+func (fx *fixer) resolveAmbiguities(filename string, language autoimport.Language, data []byte, prefs *autoimport.Preferences) (*autoimport.Preferences, error) {
+	var matches []autoimport.Match
+	if fx.daemon != nil {
+		req := fx.args.daemonRequest("importBlock", language, filename)
+		req.Source = string(data)
+		req.Preferences = prefs
+		resp, err := fx.daemon.call(req)
+		if err != nil {
+			return nil, err
+		}
+		matches = resp.Matches
+	} else {
+		ima, ok := fx.matchers[matcherKey{language, fx.buildFileOf(filename)}]
+		if !ok {
+			// The error is reported when fixing the file
+			return prefs, nil
+		}
+		ima = ima.ForLanguage(language)
+		ima.Preferences = prefs
+		var err error
+		if matches, err = ima.ImportMatches(data); err != nil {
+			return nil, err
+		}
+	}
+	configFile := autoimport.ProjectConfigFile(filename)
+	asked := false
+	for _, m := range matches {
+		if len(m.Candidates) < 2 {
+			continue
+		}
+		fmt.Fprintf(fx.prompt, "%s:%d: %s is ambiguous\n", filename, m.Line, m.Name)
+		for i, candidate := range m.Candidates {
+			fmt.Fprintf(fx.prompt, "  %d) %s\n", i+1, candidate)
+		}
+		choice, err := fx.choose(len(m.Candidates))
+		if err != nil {
+			return nil, err
+		}
+		if err := autoimport.SavePreference(configFile, m.Name, m.Candidates[choice]); err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(fx.prompt, "Saved prefer.%s = %s in %s\n", m.Name, m.Candidates[choice], configFile)
+		asked = true
+	}
+	if !asked {
+		return prefs, nil
+	}
+	return autoimport.PreferencesForFile(filename)
+}
+
+// choose asks for the number of one of the n candidates, and returns its index.
+// An empty answer chooses the first candidate, which is the best ranked one.
+func (fx *fixer) choose(n int) (int, error) {
+	for {
+		fmt.Fprintf(fx.prompt, "Import which one? [1-%d, default 1]: ", n)
+		line, err := fx.input.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if answer == "" && err != nil {
+			fmt.Fprintln(fx.prompt)
+			return 0, errors.New("no answer for an ambiguous class name")
+		}
+		if answer == "" {
+			return 0, nil
+		}
+		if i, convErr := strconv.Atoi(answer); convErr == nil && i >= 1 && i <= n {
+			return i - 1, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("invalid answer: %q", answer)
+		}
+		fmt.Fprintf(fx.prompt, "Please enter a number from 1 to %d\n", n)
+	}
+}
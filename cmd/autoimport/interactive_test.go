@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xyproto/autoimport"
+)
+
+func TestInteractive(t *testing.T) {
+	// The global configuration file is found with os.UserConfigDir
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	libPath := t.TempDir()
+	writeTestJAR(t, filepath.Join(libPath, "rt.jar"), map[string][]byte{"java/util/Date.class": nil, "java/sql/Date.class": nil, "java/util/ArrayList.class": nil})
+	ima, err := autoimport.NewWithOptions(autoimport.Options{Language: autoimport.Java, JARPaths: []string{libPath}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	projectDir := t.TempDir()
+	source := "package main;\n\nclass Main {\n    Date date;\n    ArrayList<String> list;\n}\n"
+	var filenames []string
+	for _, name := range []string{"Main.java", "Other.java"} {
+		filename := filepath.Join(projectDir, name)
+		if err := os.WriteFile(filename, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		filenames = append(filenames, filename)
+	}
+
+	var prompt bytes.Buffer
+	fx := &fixer{
+		args:     &Args{Write: true, Interactive: true},
+		matchers: map[matcherKey]*autoimport.ImportMatcher{{language: autoimport.Java}: ima},
+		errs:     make(map[matcherKey]error),
+		input:    bufio.NewReader(strings.NewReader("3\n2\n")),
+		prompt:   &prompt,
+	}
+	for _, filename := range filenames {
+		result := fixResult{done: make(chan struct{})}
+		fx.fixFile(filename, &result)
+		if result.err != nil {
+			t.Fatalf("Could not fix %s: %v\n", filename, result.err)
+		}
+		expected := "package main;\n\nimport java.util.*; // ArrayList, Date\n\nclass Main {\n    Date date;\n    ArrayList<String> list;\n}\n"
+		if string(result.newData) != expected {
+			t.Fatalf("Expected\n%s\ngot\n%s\n", expected, result.newData)
+		}
+	}
+	// The answer is only asked for once, and an invalid answer is asked again
+	if got := strings.Count(prompt.String(), "Date is ambiguous"); got != 1 {
+		t.Fatalf("Expected one question, got %d:\n%s\n", got, prompt.String())
+	}
+	if !strings.Contains(prompt.String(), "Please enter a number from 1 to 2") {
+		t.Fatalf("Expected the invalid answer to be rejected:\n%s\n", prompt.String())
+	}
+	data, err := os.ReadFile(filepath.Join(projectDir, autoimport.ConfigFilename))
+	if err != nil || string(data) != "prefer.Date = java.util.Date\n" {
+		t.Fatalf("Unexpected configuration file: %q (%v)\n", data, err)
+	}
+}
@@ -0,0 +1,710 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/alexflint/go-arg"
+	"github.com/xyproto/autoimport"
+)
+
+// maxCompletionItems is the maximum number of class names that are returned for a completion request
+const maxCompletionItems = 200
+
+// LSPArgs defines the possible command line arguments for "autoimport lsp"
+type LSPArgs struct {
+	Dependencies bool   `arg:"-m,--dependencies" help:"also search the local Maven repository and Gradle cache"`
+	Project      bool   `arg:"--project" help:"also search the dependencies declared in the pom.xml or build.gradle(.kts) of each workspace folder"`
+	RebuildCache bool   `arg:"--rebuild-cache" help:"scan all archives again instead of using the class index cache"`
+	NoGlob       bool   `arg:"-n,--noglob" help:"generate imports without wildcards"`
+	Layout       string `arg:"--layout" help:"the import layout: lexicographic, google, intellij, ktlint or comma separated patterns (default: from the nearest .autoimport file)"`
+	Release      int    `arg:"--release" help:"the target Java release, like 17, for choosing the classes in multi-release jars (default: all releases)"`
+	Nested       bool   `arg:"--nested" help:"import nested classes that are used like Map.Entry, and shorten the names to Entry"`
+}
+
+// Description is shown at the top of the help output for "autoimport lsp"
+func (LSPArgs) Description() string {
+	return "Run a Language Server Protocol server over stdin and stdout.\n" +
+		"Provides an \"Organize imports\" code action, quick-fixes for classes that are not imported,\n" +
+		"diagnostics for missing and unused imports and completion of class names.\n"
+}
+
+// LSP types, with only the fields that are used
+
+type lspPosition struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type lspRange struct {
+	Start lspPosition `json:"start"`
+	End   lspPosition `json:"end"`
+}
+
+type lspTextEdit struct {
+	Range   lspRange `json:"range"`
+	NewText string   `json:"newText"`
+}
+
+type lspWorkspaceEdit struct {
+	Changes map[string][]lspTextEdit `json:"changes"`
+}
+
+type lspDiagnostic struct {
+	Range    lspRange `json:"range"`
+	Severity int      `json:"severity"`
+	Code     string   `json:"code,omitempty"`
+	Source   string   `json:"source"`
+	Message  string   `json:"message"`
+}
+
+type lspCodeAction struct {
+	Title       string            `json:"title"`
+	Kind        string            `json:"kind"`
+	Diagnostics []lspDiagnostic   `json:"diagnostics,omitempty"`
+	IsPreferred bool              `json:"isPreferred,omitempty"`
+	Edit        *lspWorkspaceEdit `json:"edit,omitempty"`
+}
+
+type lspCompletionItem struct {
+	Label               string        `json:"label"`
+	Kind                int           `json:"kind"`
+	Detail              string        `json:"detail,omitempty"`
+	SortText            string        `json:"sortText,omitempty"`
+	AdditionalTextEdits []lspTextEdit `json:"additionalTextEdits,omitempty"`
+}
+
+type lspCompletionList struct {
+	IsIncomplete bool                `json:"isIncomplete"`
+	Items        []lspCompletionItem `json:"items"`
+}
+
+type lspTextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type lspInitializeParams struct {
+	RootURI          string `json:"rootUri"`
+	WorkspaceFolders []struct {
+		URI string `json:"uri"`
+	} `json:"workspaceFolders"`
+}
+
+type lspDidOpenParams struct {
+	TextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text"`
+	} `json:"textDocument"`
+}
+
+type lspDidChangeParams struct {
+	TextDocument   lspTextDocumentIdentifier `json:"textDocument"`
+	ContentChanges []struct {
+		Text string `json:"text"`
+	} `json:"contentChanges"`
+}
+
+type lspDidCloseParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+}
+
+type lspCodeActionParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Range        lspRange                  `json:"range"`
+	Context      struct {
+		Diagnostics []lspDiagnostic `json:"diagnostics"`
+		Only        []string        `json:"only"`
+	} `json:"context"`
+}
+
+type lspCompletionParams struct {
+	TextDocument lspTextDocumentIdentifier `json:"textDocument"`
+	Position     lspPosition               `json:"position"`
+}
+
+// LSP constants
+const (
+	lspSyncFull                 = 1
+	lspSeverityWarning          = 2
+	lspSeverityInformation      = 3
+	lspCompletionKindClass      = 7
+	lspCodeActionOrganize       = "source.organizeImports"
+	lspCodeActionQuickFix       = "quickfix"
+	lspDiagnosticSource         = "autoimport"
+	lspMissingImportCode        = "missing-import"
+	lspUnusedImportCode         = "unused-import"
+	lspUnorderedImportsCode     = "unordered-imports"
+	lspMethodPublishDiagnostics = "textDocument/publishDiagnostics"
+)
+
+// workspaceMatcher is the ImportMatcher for a project, which is created when it is first needed
+type workspaceMatcher struct {
+	once     sync.Once
+	ima      *autoimport.ImportMatcher
+	kotlinOK bool // false if Kotlin could not be found, and the index only has the Java classes
+	err      error
+	modTime  time.Time // the latest modification time of the project source trees, when they were last searched
+}
+
+// lspServer is a Language Server Protocol server that keeps one ImportMatcher per project, or a single
+// ImportMatcher for all documents if the projects are not indexed
+type lspServer struct {
+	conn       *rpcConn
+	newMatcher func(key daemonKey) (*autoimport.ImportMatcher, bool, error)
+	args       *Args    // the --dependencies, --project and --release arguments, for finding the ImportMatcher of a document
+	roots      []string // the workspace folders
+	layout     string   // the --layout argument. If empty, the layout is read from the configuration file of each document.
+	mut        sync.Mutex
+	matchers   map[daemonKey]*workspaceMatcher // the ImportMatchers, per build file and dependency settings
+	documents  map[string]string               // the text of the open documents, per URI
+	shutdown   bool
+}
+
+// newLSPServer creates a new LSP server that reads from r and writes to w.
+// newMatcher is used for creating the ImportMatcher for a build file and dependency settings.
+func newLSPServer(r io.Reader, w io.Writer, newMatcher func(key daemonKey) (*autoimport.ImportMatcher, bool, error)) *lspServer {
+	return &lspServer{
+		conn:       newRPCConn(r, w),
+		newMatcher: newMatcher,
+		args:       &Args{},
+		matchers:   make(map[daemonKey]*workspaceMatcher),
+		documents:  make(map[string]string),
+	}
+}
+
+// lspMain runs the lsp subcommand with the given arguments, and returns the exit code
+func lspMain(arguments []string) int {
+	var lspArgs LSPArgs
+	p, err := arg.NewParser(arg.Config{Program: "autoimport lsp"}, &lspArgs)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := p.Parse(arguments); err != nil {
+		if errors.Is(err, arg.ErrHelp) {
+			p.WriteHelp(os.Stdout)
+			return 0
+		}
+		p.WriteUsage(os.Stderr)
+		fmt.Fprintln(os.Stderr, "error:", err)
+		return 1
+	}
+	if lspArgs.Layout != "" {
+		if _, err := autoimport.ParseImportLayout(lspArgs.Layout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	args := &Args{
+		Dependencies: lspArgs.Dependencies,
+		Project:      lspArgs.Project,
+		RebuildCache: lspArgs.RebuildCache,
+		NoGlob:       lspArgs.NoGlob,
+		Nested:       lspArgs.Nested,
+		Release:      lspArgs.Release,
+	}
+	server := newLSPServer(os.Stdin, os.Stdout, func(key daemonKey) (*autoimport.ImportMatcher, bool, error) {
+		opts := args.options(autoimport.Kotlin, key.buildFile)
+		if args.NoGlob {
+			opts.ImportStyle = autoimport.ExplicitImports
+		}
+		// stdout is used for the protocol
+		opts.Logger = log.New(os.Stderr, "", 0)
+		return newMatcher(opts)
+	})
+	server.args = args
+	server.layout = lspArgs.Layout
+	if err := server.run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// run handles messages until the "exit" notification is received, or the input is closed
+func (s *lspServer) run() error {
+	for {
+		msg, err := s.conn.read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			var rpcErr *rpcError
+			if errors.As(err, &rpcErr) {
+				s.conn.reply(nil, nil, rpcErr)
+				continue
+			}
+			return err
+		}
+		if msg.Method == "exit" {
+			if !s.shutdown {
+				return errors.New("exit without shutdown")
+			}
+			return nil
+		}
+		result, err := s.handle(msg)
+		if msg.ID != nil {
+			if err := s.conn.reply(msg.ID, result, err); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle handles a single request or notification, and returns the result
+func (s *lspServer) handle(msg *rpcMessage) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		var params lspInitializeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		for _, folder := range params.WorkspaceFolders {
+			s.roots = append(s.roots, uriToPath(folder.URI))
+		}
+		if len(s.roots) == 0 && params.RootURI != "" {
+			s.roots = append(s.roots, uriToPath(params.RootURI))
+		}
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   lspSyncFull,
+				"codeActionProvider": map[string]interface{}{"codeActionKinds": []string{lspCodeActionOrganize, lspCodeActionQuickFix}},
+				"completionProvider": map[string]interface{}{},
+			},
+			"serverInfo": map[string]string{"name": "autoimport", "version": strings.TrimPrefix(versionString, "autoimport ")},
+		}, nil
+	case "initialized":
+		// Index the classes of the workspace folders in the background, so that they are ready when needed
+		for _, root := range s.roots {
+			go s.matcher(s.matcherKey(root))
+		}
+		return nil, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		var params lspDidOpenParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.setDocument(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, nil
+	case "textDocument/didChange":
+		var params lspDidChangeParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.setDocument(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params lspDidCloseParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, err
+		}
+		s.mut.Lock()
+		delete(s.documents, params.TextDocument.URI)
+		s.mut.Unlock()
+		return nil, s.conn.notify(lspMethodPublishDiagnostics, map[string]interface{}{
+			"uri":         params.TextDocument.URI,
+			"diagnostics": []lspDiagnostic{},
+		})
+	case "textDocument/codeAction":
+		var params lspCodeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		return s.codeActions(params)
+	case "textDocument/completion":
+		var params lspCompletionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &rpcError{Code: rpcInvalidParams, Message: err.Error()}
+		}
+		return s.completion(params)
+	}
+	if msg.ID != nil {
+		return nil, &rpcError{Code: rpcMethodNotFound, Message: "method not found: " + msg.Method}
+	}
+	// Other notifications are ignored
+	return nil, nil
+}
+
+// setDocument stores the text of a document, and publishes the diagnostics for it
+func (s *lspServer) setDocument(uri, text string) {
+	s.mut.Lock()
+	s.documents[uri] = text
+	s.mut.Unlock()
+	s.publishDiagnostics(uri, text)
+}
+
+// document returns the text of the given open document, or the contents of the file
+func (s *lspServer) document(uri string) (string, error) {
+	s.mut.Lock()
+	text, ok := s.documents[uri]
+	s.mut.Unlock()
+	if ok {
+		return text, nil
+	}
+	data, err := os.ReadFile(uriToPath(uri))
+	return string(data), err
+}
+
+// matcherKey returns the key of the ImportMatcher for the given document or workspace folder. The documents of a
+// project share an ImportMatcher if --project is used, and all documents share one ImportMatcher if it is not.
+func (s *lspServer) matcherKey(path string) daemonKey {
+	key := daemonKey{dependencies: s.args.Dependencies, release: s.args.Release}
+	if s.args.Project {
+		key.buildFile = autoimport.FindBuildFile(path)
+	}
+	return key
+}
+
+// matcher returns the ImportMatcher for the given key, creating it if needed
+func (s *lspServer) matcher(key daemonKey) (*autoimport.ImportMatcher, bool, error) {
+	s.mut.Lock()
+	wm, ok := s.matchers[key]
+	if !ok {
+		wm = &workspaceMatcher{}
+		s.matchers[key] = wm
+	}
+	s.mut.Unlock()
+	wm.once.Do(func() {
+		wm.ima, wm.kotlinOK, wm.err = s.newMatcher(key)
+	})
+	return wm.ima, wm.kotlinOK, wm.err
+}
+
+// matcherFor returns an ImportMatcher for the language of the given document,
+// which shares the class index of the project that contains the document
+func (s *lspServer) matcherFor(uri string) (*autoimport.ImportMatcher, error) {
+	path := uriToPath(uri)
+	ima, kotlinOK, err := s.matcher(s.matcherKey(path))
+	if err != nil {
+		return nil, err
+	}
+	language := languageOf(path)
+	if language == autoimport.Kotlin && !kotlinOK {
+		return nil, autoimport.ErrNoKotlin
+	}
+	layout, err := importLayout(s.layout, path, language)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := autoimport.PreferencesForFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ima = ima.ForLanguage(language)
+	ima.Layout, ima.Preferences = layout, prefs
+	return ima, nil
+}
+
+// publishDiagnostics checks the imports of the given document, and sends the problems to the client
+func (s *lspServer) publishDiagnostics(uri, text string) {
+	diagnostics := []lspDiagnostic{}
+	if ima, err := s.matcherFor(uri); err == nil {
+		// Documents that can not be parsed (yet) are common while typing, so errors are ignored
+		problems, _ := ima.CheckImports([]byte(text))
+		lines := strings.Split(text, "\n")
+		for _, problem := range problems {
+			diagnostics = append(diagnostics, problemDiagnostic(problem, lines))
+		}
+	}
+	s.conn.notify(lspMethodPublishDiagnostics, map[string]interface{}{
+		"uri":         uri,
+		"diagnostics": diagnostics,
+	})
+}
+
+// problemDiagnostic converts a problem that is found by CheckImports to a diagnostic
+func problemDiagnostic(problem autoimport.Problem, lines []string) lspDiagnostic {
+	line := problem.Line - 1
+	var lineText string
+	if line >= 0 && line < len(lines) {
+		lineText = lines[line]
+	}
+	d := lspDiagnostic{
+		Range: lspRange{
+			Start: lspPosition{Line: line},
+			End:   lspPosition{Line: line, Character: utf16Len(lineText)},
+		},
+		Severity: lspSeverityWarning,
+		Source:   lspDiagnosticSource,
+		Message:  problem.String(),
+	}
+	switch problem.Kind {
+	case autoimport.MissingImport, autoimport.AmbiguousImport:
+		// The quick-fixes for a missing import list all the classes with the name
+		d.Code = lspMissingImportCode
+		if start := wordIndex(lineText, problem.ClassName); start >= 0 {
+			d.Range.Start.Character = utf16Len(lineText[:start])
+			d.Range.End.Character = utf16Len(lineText[:start+len(problem.ClassName)])
+		}
+	case autoimport.UnusedImport:
+		d.Code = lspUnusedImportCode
+	case autoimport.UnorderedImports:
+		d.Code = lspUnorderedImportsCode
+		d.Severity = lspSeverityInformation
+	}
+	return d
+}
+
+// codeActions returns the "Organize imports" code action for the document,
+// and quick-fixes for importing the class names in the given range
+func (s *lspServer) codeActions(params lspCodeActionParams) ([]lspCodeAction, error) {
+	actions := []lspCodeAction{}
+	uri := params.TextDocument.URI
+	text, err := s.document(uri)
+	if err != nil {
+		return nil, err
+	}
+	ima, err := s.matcherFor(uri)
+	if err != nil {
+		return nil, err
+	}
+	wants := func(kind string) bool {
+		if len(params.Context.Only) == 0 {
+			return true
+		}
+		for _, only := range params.Context.Only {
+			if kind == only || strings.HasPrefix(kind, only+".") {
+				return true
+			}
+		}
+		return false
+	}
+
+	if wants(lspCodeActionOrganize) {
+		newData, err := ima.FixImports([]byte(text), false)
+		if err == nil && string(newData) != text {
+			actions = append(actions, lspCodeAction{
+				Title: "Organize imports",
+				Kind:  lspCodeActionOrganize,
+				Edit:  &lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: {replaceEdit(text, string(newData))}}},
+			})
+		}
+	}
+
+	if wants(lspCodeActionQuickFix) {
+		lines := strings.Split(text, "\n")
+		positions := []lspPosition{params.Range.Start}
+		for _, d := range params.Context.Diagnostics {
+			if d.Source == lspDiagnosticSource && d.Code == lspMissingImportCode {
+				positions = append(positions, d.Range.Start)
+			}
+		}
+		var seen []string
+		for _, pos := range positions {
+			className := wordAt(lines, pos)
+			if className == "" || !unicode.IsUpper([]rune(className)[0]) || hasString(seen, className) {
+				continue
+			}
+			seen = append(seen, className)
+			classNames, importPaths := ima.StarPathAllExact(className)
+			for i := range classNames {
+				if importPaths[i] == "java.lang.*" {
+					continue
+				}
+				importLine := importLineFor(ima, classNames[i], importPaths[i])
+				actions = append(actions, lspCodeAction{
+					Title:       "Import " + strings.TrimSuffix(importPaths[i], "*") + classNames[i],
+					Kind:        lspCodeActionQuickFix,
+					IsPreferred: i == 0,
+					Edit:        &lspWorkspaceEdit{Changes: map[string][]lspTextEdit{uri: {insertImportEdit(lines, importLine)}}},
+				})
+			}
+		}
+	}
+	return actions, nil
+}
+
+// completion returns the class names that start with the word before the given position
+func (s *lspServer) completion(params lspCompletionParams) (*lspCompletionList, error) {
+	list := &lspCompletionList{Items: []lspCompletionItem{}}
+	uri := params.TextDocument.URI
+	text, err := s.document(uri)
+	if err != nil {
+		return nil, err
+	}
+	lines := strings.Split(text, "\n")
+	if params.Position.Line < 0 || params.Position.Line >= len(lines) {
+		return list, nil
+	}
+	lineText := lines[params.Position.Line]
+	end := byteOffset(lineText, params.Position.Character)
+	start := end
+	for start > 0 && isIdentifierByte(lineText[start-1]) {
+		start--
+	}
+	prefix := lineText[start:end]
+	if prefix == "" || !unicode.IsUpper(rune(prefix[0])) {
+		return list, nil
+	}
+	ima, err := s.matcherFor(uri)
+	if err != nil {
+		return nil, err
+	}
+	classNames, importPaths := ima.StarPathAll(prefix)
+	for i := range classNames {
+		if len(list.Items) == maxCompletionItems {
+			list.IsIncomplete = true
+			break
+		}
+		item := lspCompletionItem{
+			Label:    classNames[i],
+			Kind:     lspCompletionKindClass,
+			Detail:   strings.TrimSuffix(importPaths[i], "*") + classNames[i],
+			SortText: fmt.Sprintf("%05d", i),
+		}
+		importLine := importLineFor(ima, classNames[i], importPaths[i])
+		if importPaths[i] != "java.lang.*" && !strings.Contains(text, strings.SplitN(importLine, ";", 2)[0]) {
+			item.AdditionalTextEdits = []lspTextEdit{insertImportEdit(lines, importLine)}
+		}
+		list.Items = append(list.Items, item)
+	}
+	return list, nil
+}
+
+// importLineFor returns an import line for the given class, in the same style as FixImports
+func importLineFor(ima *autoimport.ImportMatcher, className, importPath string) string {
+	if ima.DeGlob {
+		return "import " + strings.TrimSuffix(importPath, "*") + className + ";"
+	}
+	return "import " + importPath + "; // " + className
+}
+
+// insertImportEdit returns an edit that inserts the given import line after the last import,
+// or after the package declaration if there are no imports
+func insertImportEdit(lines []string, importLine string) lspTextEdit {
+	lastImport, packageLine := -1, -1
+	for i, line := range lines {
+		trimmedLine := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmedLine, "import ") {
+			lastImport = i
+		} else if strings.HasPrefix(trimmedLine, "package ") && packageLine < 0 {
+			packageLine = i
+		}
+	}
+	switch {
+	case lastImport >= 0:
+		pos := lspPosition{Line: lastImport + 1}
+		return lspTextEdit{Range: lspRange{Start: pos, End: pos}, NewText: importLine + "\n"}
+	case packageLine >= 0:
+		pos := lspPosition{Line: packageLine + 1}
+		return lspTextEdit{Range: lspRange{Start: pos, End: pos}, NewText: "\n" + importLine + "\n"}
+	}
+	return lspTextEdit{NewText: importLine + "\n\n"}
+}
+
+// replaceEdit returns an edit that changes oldText to newText, by only replacing the lines that differ
+func replaceEdit(oldText, newText string) lspTextEdit {
+	oldLines := strings.Split(oldText, "\n")
+	newLines := strings.Split(newText, "\n")
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix && oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+	var sb strings.Builder
+	for _, line := range newLines[prefix : len(newLines)-suffix] {
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	return lspTextEdit{
+		Range: lspRange{
+			Start: lspPosition{Line: prefix},
+			End:   lspPosition{Line: len(oldLines) - suffix},
+		},
+		NewText: sb.String(),
+	}
+}
+
+// uriToPath converts a file:// URI to a path
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+// utf16Len returns the length of the given string in UTF-16 code units, which is how LSP counts characters
+func utf16Len(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// byteOffset converts a position in UTF-16 code units to a byte offset in the given line
+func byteOffset(line string, character int) int {
+	units := 0
+	for i, r := range line {
+		if units >= character {
+			return i
+		}
+		units += len(utf16.Encode([]rune{r}))
+	}
+	return len(line)
+}
+
+// isIdentifierByte checks if the given byte can be part of a Java or Kotlin identifier
+func isIdentifierByte(b byte) bool {
+	return b == '_' || b == '$' || b >= 0x80 || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
+
+// wordAt returns the identifier at the given position
+func wordAt(lines []string, pos lspPosition) string {
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return ""
+	}
+	line := lines[pos.Line]
+	start := byteOffset(line, pos.Character)
+	end := start
+	for start > 0 && isIdentifierByte(line[start-1]) {
+		start--
+	}
+	for end < len(line) && isIdentifierByte(line[end]) {
+		end++
+	}
+	return line[start:end]
+}
+
+// wordIndex returns the byte index of the first occurrence of the given word in the line, as a whole word,
+// or -1 if it is not found
+func wordIndex(line, word string) int {
+	for offset := 0; offset < len(line); {
+		i := strings.Index(line[offset:], word)
+		if i < 0 {
+			return -1
+		}
+		start, end := offset+i, offset+i+len(word)
+		if (start == 0 || !isIdentifierByte(line[start-1])) && (end == len(line) || !isIdentifierByte(line[end])) {
+			return start
+		}
+		offset = end
+	}
+	return -1
+}
+
+// hasString checks if the given slice of strings contains the given string
+func hasString(xs []string, x string) bool {
+	for _, s := range xs {
+		if s == x {
+			return true
+		}
+	}
+	return false
+}
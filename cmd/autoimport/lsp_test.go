@@ -0,0 +1,264 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xyproto/autoimport"
+)
+
+// writeTestJAR writes a .jar file with the given entries and contents to the given path
+func writeTestJAR(t *testing.T, path string, entries map[string][]byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testMatcher returns a function that creates an ImportMatcher for a small test JAR file
+func testMatcher(t *testing.T) func(daemonKey) (*autoimport.ImportMatcher, bool, error) {
+	t.Helper()
+	libPath := t.TempDir()
+	writeTestJAR(t, filepath.Join(libPath, "rt.jar"), map[string][]byte{"java/util/ArrayList.class": nil, "java/util/HashMap.class": nil, "java/awt/List.class": nil, "java/util/List.class": nil})
+	cachePath := filepath.Join(t.TempDir(), "classes.gob")
+	return func(key daemonKey) (*autoimport.ImportMatcher, bool, error) {
+		return newMatcher(autoimport.Options{
+			Language:  autoimport.Kotlin,
+			JARPaths:  []string{libPath},
+			CachePath: cachePath,
+		})
+	}
+}
+
+// lspClient sends requests to an lspServer and reads the responses
+type lspClient struct {
+	t    *testing.T
+	conn *rpcConn
+	id   int
+}
+
+func (c *lspClient) send(method string, params interface{}, isRequest bool) {
+	c.t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	msg := &rpcMessage{Method: method, Params: data}
+	if isRequest {
+		c.id++
+		id := json.RawMessage(strings.TrimSpace(string(mustMarshal(c.t, c.id))))
+		msg.ID = &id
+	}
+	if err := c.conn.write(msg); err != nil {
+		c.t.Fatal(err)
+	}
+}
+
+// receive reads the next message, and decodes the result or the params into v
+func (c *lspClient) receive(v interface{}) *rpcMessage {
+	c.t.Helper()
+	msg, err := c.conn.read()
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if msg.Error != nil {
+		c.t.Fatalf("Unexpected error: %v\n", msg.Error)
+	}
+	data := []byte(msg.Params)
+	if msg.Method == "" {
+		data = mustMarshal(c.t, msg.Result)
+	}
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			c.t.Fatal(err)
+		}
+	}
+	return msg
+}
+
+func mustMarshal(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestLSPServer(t *testing.T) {
+	root := t.TempDir()
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+	server := newLSPServer(serverReader, serverWriter, testMatcher(t))
+	done := make(chan error)
+	go func() {
+		done <- server.run()
+	}()
+	client := &lspClient{t: t, conn: newRPCConn(clientReader, clientWriter)}
+
+	client.send("initialize", map[string]interface{}{"rootUri": "file://" + filepath.ToSlash(root)}, true)
+	var initResult struct {
+		Capabilities struct {
+			TextDocumentSync int `json:"textDocumentSync"`
+		} `json:"capabilities"`
+	}
+	client.receive(&initResult)
+	if initResult.Capabilities.TextDocumentSync != lspSyncFull {
+		t.Fatalf("Unexpected capabilities: %+v\n", initResult)
+	}
+	client.send("initialized", map[string]interface{}{}, false)
+
+	uri := "file://" + filepath.ToSlash(filepath.Join(root, "Main.java"))
+	source := "package main;\n\nclass Main {\n    ArrayList<String> a;\n    Hash\n}\n"
+	client.send("textDocument/didOpen", map[string]interface{}{
+		"textDocument": map[string]interface{}{"uri": uri, "languageId": "java", "version": 1, "text": source},
+	}, false)
+	var diagnostics struct {
+		URI         string          `json:"uri"`
+		Diagnostics []lspDiagnostic `json:"diagnostics"`
+	}
+	if msg := client.receive(&diagnostics); msg.Method != lspMethodPublishDiagnostics {
+		t.Fatalf("Expected diagnostics, got %+v\n", msg)
+	}
+	if len(diagnostics.Diagnostics) != 1 || diagnostics.Diagnostics[0].Code != lspMissingImportCode {
+		t.Fatalf("Expected a missing import, got %+v\n", diagnostics.Diagnostics)
+	}
+	d := diagnostics.Diagnostics[0]
+	if d.Range.Start != (lspPosition{Line: 3, Character: 4}) || d.Range.End != (lspPosition{Line: 3, Character: 13}) {
+		t.Errorf("Unexpected range for ArrayList: %+v\n", d.Range)
+	}
+
+	// Organize imports
+	client.send("textDocument/codeAction", map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"range":        lspRange{},
+		"context":      map[string]interface{}{"diagnostics": []lspDiagnostic{}, "only": []string{lspCodeActionOrganize}},
+	}, true)
+	var actions []lspCodeAction
+	client.receive(&actions)
+	if len(actions) != 1 || actions[0].Kind != lspCodeActionOrganize {
+		t.Fatalf("Expected an organize imports action, got %+v\n", actions)
+	}
+	if edits := actions[0].Edit.Changes[uri]; len(edits) != 1 || !strings.Contains(edits[0].NewText, "import java.util.*; // ArrayList") {
+		t.Errorf("Unexpected edit: %+v\n", edits)
+	}
+
+	// Quick-fix for the diagnostic
+	client.send("textDocument/codeAction", map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"range":        d.Range,
+		"context":      map[string]interface{}{"diagnostics": []lspDiagnostic{d}, "only": []string{lspCodeActionQuickFix}},
+	}, true)
+	client.receive(&actions)
+	if len(actions) != 1 || actions[0].Title != "Import java.util.ArrayList" {
+		t.Fatalf("Expected a quick-fix for ArrayList, got %+v\n", actions)
+	}
+	edit := actions[0].Edit.Changes[uri][0]
+	if edit.Range.Start != (lspPosition{Line: 1}) || edit.NewText != "\nimport java.util.*; // ArrayList\n" {
+		t.Errorf("Unexpected quick-fix edit: %+v\n", edit)
+	}
+
+	// Completion of "Hash"
+	client.send("textDocument/completion", map[string]interface{}{
+		"textDocument": map[string]string{"uri": uri},
+		"position":     lspPosition{Line: 4, Character: 8},
+	}, true)
+	var list lspCompletionList
+	client.receive(&list)
+	if len(list.Items) != 1 || list.Items[0].Label != "HashMap" || list.Items[0].Detail != "java.util.HashMap" {
+		t.Fatalf("Expected HashMap, got %+v\n", list)
+	}
+	if len(list.Items[0].AdditionalTextEdits) != 1 {
+		t.Errorf("Expected an edit that adds the import, got %+v\n", list.Items[0])
+	}
+
+	client.send("shutdown", nil, true)
+	client.receive(nil)
+	client.send("exit", nil, false)
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error when exiting: %v\n", err)
+	}
+}
+
+func TestLSPMatcherKeys(t *testing.T) {
+	projectA, projectB := t.TempDir(), t.TempDir()
+	for _, projectPath := range []string{projectA, projectB} {
+		if err := os.WriteFile(filepath.Join(projectPath, "pom.xml"), []byte("<project></project>"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	uris := []string{
+		"file://" + filepath.ToSlash(filepath.Join(projectA, "src", "main", "java", "a", "Main.java")),
+		"file://" + filepath.ToSlash(filepath.Join(projectA, "src", "test", "java", "a", "MainTest.java")),
+		"file://" + filepath.ToSlash(filepath.Join(projectB, "Other.java")),
+		"file://" + filepath.ToSlash(filepath.Join(t.TempDir(), "Scratch.java")),
+	}
+	newTestMatcher := testMatcher(t)
+	for _, test := range []struct {
+		project  bool
+		expected int
+	}{
+		{false, 1}, // one ImportMatcher is shared by all documents
+		{true, 3},  // one ImportMatcher per project, and one for documents outside of any project
+	} {
+		var keys []daemonKey
+		server := newLSPServer(nil, io.Discard, func(key daemonKey) (*autoimport.ImportMatcher, bool, error) {
+			keys = append(keys, key)
+			return newTestMatcher(key)
+		})
+		server.args = &Args{Project: test.project, Dependencies: true}
+		for _, uri := range uris {
+			if _, err := server.matcherFor(uri); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if len(keys) != test.expected {
+			t.Errorf("Expected %d ImportMatchers with --project=%v, got %v\n", test.expected, test.project, keys)
+		}
+		for _, key := range keys {
+			if !key.dependencies {
+				t.Errorf("Expected the dependency settings to be a part of the key, got %+v\n", key)
+			}
+		}
+	}
+}
+
+func TestLSPParseError(t *testing.T) {
+	body := "{not json"
+	input := fmt.Sprintf("Content-Length: %d\r\n\r\n%s", len(body), body)
+	var output strings.Builder
+	server := newLSPServer(strings.NewReader(input), &output, testMatcher(t))
+	if err := server.run(); err != nil {
+		t.Fatal(err)
+	}
+	response := output.String()
+	if !strings.Contains(response, `"id":null`) || !strings.Contains(response, fmt.Sprintf(`"code":%d`, rpcParseError)) {
+		t.Fatalf("Expected a parse error with a null ID, got %s\n", response)
+	}
+}
+
+func TestReplaceEdit(t *testing.T) {
+	edit := replaceEdit("package a;\n\nclass A {}\n", "package a;\n\nimport b.*;\n\nclass A {}\n")
+	if edit.Range.Start != (lspPosition{Line: 2}) || edit.Range.End != (lspPosition{Line: 2}) || edit.NewText != "import b.*;\n\n" {
+		t.Errorf("Unexpected edit: %+v\n", edit)
+	}
+}
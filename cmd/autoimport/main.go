@@ -1,9 +1,12 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
 	"os"
-	"strings"
+	"path/filepath"
 
 	"github.com/alexflint/go-arg"
 	"github.com/xyproto/autoimport"
@@ -13,13 +16,27 @@ const versionString = "autoimport 1.0.0"
 
 // Args defines the possible command line arguments
 type Args struct {
-	StartOfClassName  string `arg:"positional"`
-	SourceFile        string `arg:"-f,--file"`
-	ShortestMatchOnly bool   `arg:"-s,--shortest"`
-	JavaOnly          bool   `arg:"-j,--java"`
-	Exact             bool   `arg:"-e,--exact"`
-	Verbose           bool   `arg:"-V,--verbose"`
-	NoGlob            bool   `arg:"-n,--noglob"`
+	Positional        []string `arg:"positional" placeholder:"CLASSNAME|PATH" help:"the start of a class name, or the files and directories to fix with -w, -d or -l"`
+	SourceFile        string   `arg:"-f,--file"`
+	ShortestMatchOnly bool     `arg:"-s,--shortest"`
+	JavaOnly          bool     `arg:"-j,--java"`
+	Exact             bool     `arg:"-e,--exact"`
+	Verbose           bool     `arg:"-V,--verbose"`
+	NoGlob            bool     `arg:"-n,--noglob"`
+	Dependencies      bool     `arg:"-m,--dependencies" help:"also search the local Maven repository and Gradle cache"`
+	Project           bool     `arg:"--project" help:"also search the dependencies declared in the nearest pom.xml or build.gradle(.kts)"`
+	RebuildCache      bool     `arg:"--rebuild-cache" help:"scan all archives again instead of using the class index cache"`
+	Write             bool     `arg:"-w,--write" help:"fix the imports of the given files, and write the result to the files"`
+	Diff              bool     `arg:"-d,--diff" help:"output a diff of the import changes for the given files"`
+	List              bool     `arg:"-l,--list" help:"list the given files with imports that would be changed"`
+	Jobs              int      `arg:"--jobs" help:"the number of files to fix in parallel (default: the number of CPUs)"`
+	Socket            string   `arg:"--socket" help:"the Unix domain socket of a running daemon (see autoimport serve)"`
+	NoDaemon          bool     `arg:"--no-daemon" help:"index the classes in this process, even if a daemon is running"`
+	JSON              bool     `arg:"--json" help:"output JSON records, one per line"`
+	Layout            string   `arg:"--layout" help:"the import layout: lexicographic, google, intellij, ktlint or comma separated patterns (default: from the nearest .autoimport file)"`
+	Nested            bool     `arg:"--nested" help:"import nested classes that are used like Map.Entry, and shorten the names to Entry, instead of importing the outer classes"`
+	Release           int      `arg:"--release" help:"the target Java release, like 17, for choosing the classes in multi-release jars (default: all releases)"`
+	Interactive       bool     `arg:"-i,--interactive" help:"with -w, -d or -l, ask which class to import when a class name is ambiguous, and remember the answer in the .autoimport file of the project"`
 }
 
 // Version will output the current program name and version
@@ -27,27 +44,83 @@ func (Args) Version() string {
 	return versionString
 }
 
+// options returns the ImportMatcher options for the given language,
+// and for the given file or directory if --project is used
+func (args *Args) options(language autoimport.Language, projectFile string) autoimport.Options {
+	opts := autoimport.Options{
+		Language:      language,
+		Dependencies:  args.Dependencies,
+		RebuildCache:  args.RebuildCache,
+		NestedImports: args.Nested,
+		Release:       args.Release,
+		// stdout may be used for the fixed source code, so verbose output and problems with the
+		// dependencies of the project are written to stderr
+		Logger: log.New(os.Stderr, "", 0),
+	}
+	if args.Project {
+		opts.ProjectFile = projectFile
+	}
+	return opts
+}
+
+// fail outputs the given error to stderr, with a hint if Java or Kotlin could not be found, and exits
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	switch {
+	case errors.Is(err, autoimport.ErrNoJava):
+		fmt.Fprintln(os.Stderr, "Install a JDK, or set JAVA_HOME to the path of one.")
+	case errors.Is(err, autoimport.ErrNoKotlin):
+		fmt.Fprintln(os.Stderr, "Install Kotlin and place kotlinc in the PATH, or use -j to only search for Java classes.")
+	}
+	os.Exit(1)
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "check":
+			os.Exit(checkMain(os.Args[2:]))
+		case "lsp":
+			os.Exit(lspMain(os.Args[2:]))
+		case "serve":
+			os.Exit(serveMain(os.Args[2:]))
+		}
+	}
+
 	var args Args
 	arg.MustParse(&args)
 
-	var ima *autoimport.ImportMatcher
-	var err error
+	if args.Layout != "" {
+		if _, err := autoimport.ParseImportLayout(args.Layout); err != nil {
+			fail(err)
+		}
+	}
 
-	if args.SourceFile != "" {
-		if strings.HasSuffix(args.SourceFile, ".kt") {
-			ima, err = autoimport.New(false)
-		} else {
-			ima, err = autoimport.New(true)
+	if args.Write || args.Diff || args.List {
+		if len(args.Positional) == 0 {
+			fmt.Fprintln(os.Stderr, "no files or directories given")
+			os.Exit(1)
 		}
-		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
+		if _, ok := newFixer(&args).fixFiles(args.Positional); !ok {
 			os.Exit(1)
 		}
-		imports, err := ima.FileImports(args.SourceFile, args.Verbose)
+		return
+	}
+
+	if args.SourceFile != "" {
+		if args.JSON {
+			matches, err := args.fileMatches(args.SourceFile)
+			if err != nil {
+				fail(err)
+			}
+			for _, m := range matches {
+				printJSON(fileMatch{File: args.SourceFile, Match: m})
+			}
+			return
+		}
+		imports, err := args.fileImports(args.SourceFile)
 		if err != nil {
-			fmt.Fprintln(os.Stderr, err)
-			os.Exit(1)
+			fail(err)
 		}
 		if args.NoGlob {
 			for _, deGlobbedImport := range autoimport.DeGlob(imports) {
@@ -59,43 +132,197 @@ func main() {
 		return
 	}
 
-	ima, err = autoimport.New(args.JavaOnly)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, err)
+	if len(args.Positional) != 1 {
+		fmt.Fprintln(os.Stderr, "expected the start of a class name")
 		os.Exit(1)
 	}
+	startOfClassName := args.Positional[0]
 
-	var foundClasses, foundImports []string
-	if args.ShortestMatchOnly {
-		// Output a single class + import, if found
-		var foundClass, foundImport string
-		foundClass, foundImport = ima.StarPath(args.StartOfClassName)
-		if foundClass == "" {
-			fmt.Fprintf(os.Stderr, "could not find the %s class\n", args.StartOfClassName)
-			os.Exit(1)
+	matches, err := args.lookup(startOfClassName)
+	if err != nil {
+		fail(err)
+	}
+	if len(matches) == 0 {
+		if args.ShortestMatchOnly || args.Exact {
+			fmt.Fprintf(os.Stderr, "could not find the %s class\n", startOfClassName)
+		} else {
+			fmt.Fprintf(os.Stderr, "found no class starting with %s\n", startOfClassName)
 		}
-		fmt.Printf("import %s; // %s\n", foundImport, foundClass)
-		return
+		os.Exit(1)
 	}
+	for _, m := range matches {
+		if args.JSON {
+			printJSON(m)
+		} else {
+			fmt.Printf("import %s; // %s\n", m.Import, m.Name)
+		}
+	}
+}
+
+// fileMatch is a JSON record for a class that is used in a source file
+type fileMatch struct {
+	File string `json:"file"`
+	autoimport.Match
+}
 
-	// Output several classes and imports, if found
+// printJSON outputs the given value as JSON, on a single line
+func printJSON(v interface{}) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		fail(err)
+	}
+}
 
-	if args.Exact {
-		foundClasses, foundImports = ima.StarPathAllExact(args.StartOfClassName)
-		if len(foundClasses) == 0 {
-			fmt.Fprintf(os.Stderr, "could not find the %s class\n", args.StartOfClassName)
-			os.Exit(1)
+// fileMatches finds the classes that the given source file needs imports for,
+// using the daemon if it is running
+func (args *Args) fileMatches(filename string) ([]autoimport.Match, error) {
+	language := languageOf(filename)
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	prefs, err := autoimport.PreferencesForFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	if client := args.daemon(); client != nil {
+		req := args.daemonRequest("importBlock", language, filename)
+		req.Source = string(data)
+		req.Preferences = prefs
+		resp, err := client.call(req)
+		return resp.Matches, err
+	}
+	ima, err := autoimport.NewWithOptions(args.options(language, filename))
+	if err != nil {
+		return nil, err
+	}
+	ima.DeGlob = args.NoGlob
+	ima.Preferences = prefs
+	matches, err := ima.ImportMatches(data)
+	if err != nil {
+		var parseError *autoimport.ParseError
+		if errors.As(err, &parseError) && parseError.Filename == "" {
+			parseError.Filename = filename
 		}
-	} else {
-		foundClasses, foundImports = ima.StarPathAll(args.StartOfClassName)
-		if len(foundClasses) == 0 {
-			fmt.Fprintf(os.Stderr, "found no class starting with %s\n", args.StartOfClassName)
-			os.Exit(1)
+	}
+	return matches, err
+}
+
+// fileImports generates the import block for the given source file,
+// using the daemon if it is running
+func (args *Args) fileImports(filename string) (string, error) {
+	language := languageOf(filename)
+	layout, err := importLayout(args.Layout, filename, language)
+	if err != nil {
+		return "", err
+	}
+	prefs, err := autoimport.PreferencesForFile(filename)
+	if err != nil {
+		return "", err
+	}
+	if client := args.daemon(); client != nil {
+		data, err := os.ReadFile(filename)
+		if err != nil {
+			return "", err
 		}
+		req := args.daemonRequest("importBlock", language, filename)
+		req.Source = string(data)
+		req.setLayout(layout)
+		req.Preferences = prefs
+		resp, err := client.call(req)
+		return resp.Source, err
+	}
+	ima, err := autoimport.NewWithOptions(args.options(language, filename))
+	if err != nil {
+		return "", err
+	}
+	ima.Layout, ima.Preferences = layout, prefs
+	return ima.FileImports(filename, args.Verbose)
+}
+
+// importLayout returns the import layout for the given source file: the given --layout argument if it is set,
+// or else the layout from the nearest configuration file, if any
+func importLayout(layoutArg, filename string, language autoimport.Language) (*autoimport.ImportLayout, error) {
+	if layoutArg != "" {
+		return autoimport.ParseImportLayout(layoutArg)
 	}
-	for i := range foundClasses {
-		foundClass := foundClasses[i]
-		foundImport := foundImports[i]
-		fmt.Printf("import %s; // %s\n", foundImport, foundClass)
+	return autoimport.LayoutForFile(filename, language)
+}
+
+// lookup finds the classes that match the given class name (or the start of it, if -e is not used),
+// using the daemon if it is running. If -s is used, only the shortest match is returned.
+func (args *Args) lookup(startOfClassName string) ([]autoimport.Match, error) {
+	language := autoimport.Kotlin
+	if args.JavaOnly {
+		language = autoimport.Java
+	}
+	if client := args.daemon(); client != nil {
+		req := args.daemonRequest("lookup", language, ".")
+		req.Name = startOfClassName
+		req.Exact = args.Exact
+		req.Shortest = args.ShortestMatchOnly
+		resp, err := client.call(req)
+		return resp.Matches, err
+	}
+	ima, err := autoimport.NewWithOptions(args.options(language, "."))
+	if err != nil {
+		return nil, err
+	}
+	return lookupMatches(ima, startOfClassName, args.Exact, args.ShortestMatchOnly), nil
+}
+
+// lookupMatches finds the classes that match the given class name, or the start of it if exact is false.
+// If shortest is true, only the shortest matching class name with the best ranked import is returned.
+func lookupMatches(ima *autoimport.ImportMatcher, startOfClassName string, exact, shortest bool) []autoimport.Match {
+	switch {
+	case shortest:
+		if foundClass, _ := ima.StarPath(startOfClassName); foundClass != "" {
+			return ima.MatchesExact(foundClass)[:1]
+		}
+		return []autoimport.Match{}
+	case exact:
+		return ima.MatchesExact(startOfClassName)
+	}
+	return ima.Matches(startOfClassName)
+}
+
+// daemon returns a client for the running daemon, or nil if no daemon is running or if it should not be used.
+// Verbose output and rebuilding the cache are only possible without the daemon.
+func (args *Args) daemon() *daemonClient {
+	if args.NoDaemon || args.Verbose || args.RebuildCache {
+		return nil
+	}
+	socketPath := args.Socket
+	if socketPath == "" {
+		socketPath = defaultSocketPath()
+	}
+	client := &daemonClient{socketPath: socketPath}
+	if client.ping() != nil {
+		return nil
+	}
+	return client
+}
+
+// daemonRequest creates a daemon request with the given method, for the given language and
+// source file (or directory), with the options from the command line arguments
+func (args *Args) daemonRequest(method string, language autoimport.Language, filename string) daemonRequest {
+	req := daemonRequest{
+		Method:       method,
+		Java:         language == autoimport.Java,
+		Dependencies: args.Dependencies,
+		NoGlob:       args.NoGlob,
+		Nested:       args.Nested,
+		Release:      args.Release,
+	}
+	if filename != "." {
+		req.Filename = filename
+	}
+	if args.Project {
+		// The daemon may have a different working directory
+		if absPath, err := filepath.Abs(filename); err == nil {
+			req.ProjectFile = absPath
+		}
 	}
+	return req
 }
@@ -0,0 +1,28 @@
+//go:build !unix
+
+package main
+
+import (
+	"net"
+	"os"
+)
+
+// listenSocket listens to the given Unix domain socket, and lets only the current user connect to it,
+// where the platform supports file permissions for sockets
+func listenSocket(socketPath string) (net.Listener, error) {
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(socketPath, 0o600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// checkPrivate returns an error if other users have access to the given file,
+// where the platform supports file permissions for sockets
+func checkPrivate(path string, fi os.FileInfo) error {
+	return nil
+}
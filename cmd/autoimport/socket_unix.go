@@ -0,0 +1,31 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenSocket listens to the given Unix domain socket. The socket is created with a umask that only lets
+// the current user connect, so that there is no window where other users can connect before it is chmodded.
+// The umask is set for the whole process, so this should be called before other goroutines create files.
+func listenSocket(socketPath string) (net.Listener, error) {
+	oldMask := syscall.Umask(0o177)
+	defer syscall.Umask(oldMask)
+	return net.Listen("unix", socketPath)
+}
+
+// checkPrivate returns an error if the given file is owned by another user,
+// or if other users have access to it
+func checkPrivate(path string, fi os.FileInfo) error {
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok && int(st.Uid) != os.Getuid() {
+		return fmt.Errorf("%s is owned by another user", path)
+	}
+	if fi.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf("%s is accessible by other users (mode %v)", path, fi.Mode().Perm())
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package autoimport
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ConfigFilename is the name of the per-project configuration file, which is searched for
+// in the directory of the source file and then in each parent directory
+const ConfigFilename = ".autoimport"
+
+// Config contains the settings from a configuration file. The file has one "key = value" setting per line,
+// and lines starting with "#" are comments:
+//
+//	# the import layout for both Java and Kotlin
+//	layout = google
+//	# the import layout for Kotlin only
+//	kotlin_layout = ktlint
+//	# a custom named layout, that can be used by the settings above
+//	layout.mine = static *,|,java.**,javax.**,|,*,|,$project
+//	# the packages to import from when a class name is ambiguous, most preferred first
+//	prefer = java.util, java.time
+//	# the class (or package) to import for a specific ambiguous class name
+//	prefer.Element = org.w3c.dom.Element
+type Config struct {
+	Path         string            // the path of the configuration file
+	Layout       string            // the import layout for both Java and Kotlin
+	JavaLayout   string            // the import layout for Java, if it should be different
+	KotlinLayout string            // the import layout for Kotlin, if it should be different
+	Layouts      map[string]string // custom named import layouts
+	Preferences  Preferences       // which classes to import when class names are ambiguous
+}
+
+// ReadConfig reads the given configuration file
+func ReadConfig(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	config := &Config{Path: path, Layouts: make(map[string]string), Preferences: Preferences{Classes: make(map[string]string)}}
+	scanner := bufio.NewScanner(f)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.SplitN(line, "=", 2)
+		if len(fields) != 2 {
+			return nil, &ConfigError{Path: path, Line: lineNumber, Err: fmt.Errorf("expected \"key = value\", got %q", line)}
+		}
+		key, value := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		switch {
+		case key == "layout":
+			config.Layout = value
+		case key == "java_layout":
+			config.JavaLayout = value
+		case key == "kotlin_layout":
+			config.KotlinLayout = value
+		case strings.HasPrefix(key, "layout.") && len(key) > len("layout."):
+			config.Layouts[strings.TrimPrefix(key, "layout.")] = value
+		case key == "prefer":
+			for _, prefix := range strings.Split(value, ",") {
+				if prefix = strings.TrimSpace(prefix); prefix != "" {
+					config.Preferences.Packages = append(config.Preferences.Packages, prefix)
+				}
+			}
+		case strings.HasPrefix(key, "prefer.") && len(key) > len("prefer."):
+			config.Preferences.Classes[strings.TrimPrefix(key, "prefer.")] = value
+		default:
+			return nil, &ConfigError{Path: path, Line: lineNumber, Err: fmt.Errorf("unknown setting: %q", key)}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// FindConfigFile searches the directory of the given source file (or the given directory), and then each
+// parent directory, for an .autoimport file. If none is found, autoimport/config in the user configuration
+// directory (like ~/.config) is used, if it exists. Returns an empty string if no configuration file is found.
+// Use ConfigForFile to read the settings of both files.
+func FindConfigFile(sourceFilename string) string {
+	if configFile := findProjectConfigFile(sourceFilename); configFile != "" {
+		return configFile
+	}
+	return globalConfigFile()
+}
+
+// globalConfigFile returns the path of autoimport/config in the user configuration directory (like ~/.config),
+// or an empty string if it does not exist
+func globalConfigFile() string {
+	if configDir, err := os.UserConfigDir(); err == nil {
+		if configFile := filepath.Join(configDir, "autoimport", "config"); exists(configFile) {
+			return configFile
+		}
+	}
+	return ""
+}
+
+// ConfigForFile reads the configuration for the given source file: the settings in autoimport/config in the
+// user configuration directory (like ~/.config), with the settings of the nearest .autoimport file on top.
+// If the .autoimport file sets a layout, the layouts of the global configuration file are not used, while
+// preferences are combined, where the preferred packages of the .autoimport file come first.
+// Returns nil if no configuration file is found.
+func ConfigForFile(sourceFilename string) (*Config, error) {
+	var global, project *Config
+	if configFile := globalConfigFile(); configFile != "" {
+		config, err := ReadConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		global = config
+	}
+	if configFile := findProjectConfigFile(sourceFilename); configFile != "" {
+		config, err := ReadConfig(configFile)
+		if err != nil {
+			return nil, err
+		}
+		project = config
+	}
+	switch {
+	case project == nil:
+		return global, nil
+	case global == nil:
+		return project, nil
+	}
+	if project.Layout == "" && project.JavaLayout == "" && project.KotlinLayout == "" {
+		project.Layout, project.JavaLayout, project.KotlinLayout = global.Layout, global.JavaLayout, global.KotlinLayout
+	}
+	for name, layout := range global.Layouts {
+		if _, ok := project.Layouts[name]; !ok {
+			project.Layouts[name] = layout
+		}
+	}
+	for _, prefix := range global.Preferences.Packages {
+		if !hasS(project.Preferences.Packages, prefix) {
+			project.Preferences.Packages = append(project.Preferences.Packages, prefix)
+		}
+	}
+	for className, classPath := range global.Preferences.Classes {
+		if _, ok := project.Preferences.Classes[className]; !ok {
+			project.Preferences.Classes[className] = classPath
+		}
+	}
+	return project, nil
+}
+
+// findProjectConfigFile searches the directory of the given source file (or the given directory),
+// and then each parent directory, for an .autoimport file
+func findProjectConfigFile(sourceFilename string) string {
+	absPath, err := filepath.Abs(sourceFilename)
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Dir(absPath)
+	if isDir(absPath) {
+		dir = absPath
+	}
+	for ; ; dir = filepath.Dir(dir) {
+		if configFile := filepath.Join(dir, ConfigFilename); exists(configFile) && !isDir(configFile) {
+			return configFile
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			return ""
+		}
+	}
+}
+
+// ProjectConfigFile returns the path of the .autoimport file that settings for the project of the given
+// source file should be saved to: the nearest existing .autoimport file, or else a new one next to the
+// build file of the project, or in the directory of the source file if no build file is found
+func ProjectConfigFile(sourceFilename string) string {
+	if configFile := findProjectConfigFile(sourceFilename); configFile != "" {
+		return configFile
+	}
+	if buildFile := FindBuildFile(sourceFilename); buildFile != "" {
+		return filepath.Join(filepath.Dir(buildFile), ConfigFilename)
+	}
+	if isDir(sourceFilename) {
+		return filepath.Join(sourceFilename, ConfigFilename)
+	}
+	return filepath.Join(filepath.Dir(sourceFilename), ConfigFilename)
+}
+
+// SavePreference sets the class to import for the given ambiguous class name in the given configuration file,
+// like "prefer.Date = java.util.Date". An existing setting for the class name is replaced, and the file is
+// created if it does not exist.
+func SavePreference(configFile, className, classPath string) error {
+	data, err := os.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	setting := "prefer." + className + " = " + classPath
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	replaced := false
+	for i, line := range lines {
+		if fields := strings.SplitN(line, "=", 2); len(fields) == 2 && strings.TrimSpace(fields[0]) == "prefer."+className {
+			lines[i], replaced = setting, true
+		}
+	}
+	if !replaced {
+		lines = append(lines, setting)
+	}
+	return os.WriteFile(configFile, []byte(strings.Join(lines, "\n")+"\n"), 0o644)
+}
+
+// ImportLayout returns the configured import layout for the given language, or nil if none is configured.
+// The layout can be the name of a custom layout in the configuration file, one of the ImportLayouts,
+// or a list of patterns.
+func (config *Config) ImportLayout(language Language) (*ImportLayout, error) {
+	s := config.Layout
+	if language == Java && config.JavaLayout != "" {
+		s = config.JavaLayout
+	} else if language == Kotlin && config.KotlinLayout != "" {
+		s = config.KotlinLayout
+	}
+	if s == "" {
+		return nil, nil
+	}
+	if custom, ok := config.Layouts[s]; ok {
+		s = custom
+	}
+	layout, err := ParseImportLayout(s)
+	if err != nil {
+		return nil, &ConfigError{Path: config.Path, Err: err}
+	}
+	return layout, nil
+}
+
+// LayoutForFile returns the import layout that is configured for the given source file and language,
+// or nil if no configuration file is found or if no layout is configured. See ConfigForFile.
+func LayoutForFile(sourceFilename string, language Language) (*ImportLayout, error) {
+	config, err := ConfigForFile(sourceFilename)
+	if config == nil || err != nil {
+		return nil, err
+	}
+	return config.ImportLayout(language)
+}
+
+// PreferencesForFile returns the preferences for ambiguous class names that are configured for the given
+// source file, or nil if no configuration file is found or if no preferences are configured. See ConfigForFile.
+func PreferencesForFile(sourceFilename string) (*Preferences, error) {
+	config, err := ConfigForFile(sourceFilename)
+	if config == nil || err != nil {
+		return nil, err
+	}
+	if len(config.Preferences.Classes) == 0 && len(config.Preferences.Packages) == 0 {
+		return nil, nil
+	}
+	return &config.Preferences, nil
+}
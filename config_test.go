@@ -0,0 +1,142 @@
+package autoimport
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLayoutForFile(t *testing.T) {
+	setenv(t, "XDG_CONFIG_HOME", t.TempDir())
+	projectDir := t.TempDir()
+	sourceDir := filepath.Join(projectDir, "src", "main")
+	if err := os.MkdirAll(sourceDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sourceFile := filepath.Join(sourceDir, "Main.java")
+
+	layout, err := LayoutForFile(sourceFile, Java)
+	if err != nil || layout != nil {
+		t.Fatalf("Expected no layout without a configuration file, got %v (%v)\n", layout, err)
+	}
+
+	config := `# import layouts
+layout = google
+kotlin_layout = mine
+layout.mine = *,|,java.**,javax.**,kotlin.**,|,^
+`
+	if err := os.WriteFile(filepath.Join(projectDir, ConfigFilename), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layout, err = LayoutForFile(sourceFile, Java)
+	if err != nil || layout == nil || layout.String() != ImportLayouts["google"] {
+		t.Fatalf("Expected the google layout, got %v (%v)\n", layout, err)
+	}
+	layout, err = LayoutForFile(sourceFile, Kotlin)
+	if err != nil || layout == nil || layout.String() != "*,|,java.**,javax.**,kotlin.**,|,^" {
+		t.Fatalf("Expected the custom layout, got %v (%v)\n", layout, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(projectDir, ConfigFilename), []byte("layout = google\nstyle = tabs\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var configError *ConfigError
+	if _, err := LayoutForFile(sourceFile, Java); !errors.As(err, &configError) || configError.Line != 2 || configError.Path != filepath.Join(projectDir, ConfigFilename) {
+		t.Fatalf("Expected a configuration error on line 2, got %v\n", err)
+	}
+}
+
+func TestSavePreference(t *testing.T) {
+	setenv(t, "XDG_CONFIG_HOME", t.TempDir())
+	projectDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectDir, "pom.xml"), []byte("<project/>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sourceDir := filepath.Join(projectDir, "src", "main", "java")
+	if err := os.MkdirAll(sourceDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	sourceFile := filepath.Join(sourceDir, "Main.java")
+	configFile := ProjectConfigFile(sourceFile)
+	if expected := filepath.Join(projectDir, ConfigFilename); configFile != expected {
+		t.Fatalf("Expected %s, got %s\n", expected, configFile)
+	}
+	if prefs, err := PreferencesForFile(sourceFile); err != nil || prefs != nil {
+		t.Fatalf("Expected no preferences, got %v (%v)\n", prefs, err)
+	}
+
+	if err := SavePreference(configFile, "Date", "java.sql.Date"); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := os.ReadFile(configFile); err != nil || string(data) != "prefer.Date = java.sql.Date\n" {
+		t.Fatalf("Unexpected configuration file: %q (%v)\n", data, err)
+	}
+	if err := os.WriteFile(configFile, []byte("layout = google\nprefer = java.util, java.time\nprefer.Date = java.sql.Date\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavePreference(configFile, "Date", "java.util.Date"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SavePreference(configFile, "Element", "org.w3c.dom.Element"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "layout = google\nprefer = java.util, java.time\nprefer.Date = java.util.Date\nprefer.Element = org.w3c.dom.Element\n"; string(data) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, data)
+	}
+	prefs, err := PreferencesForFile(sourceFile)
+	if err != nil || prefs == nil {
+		t.Fatalf("Could not read the preferences: %v\n", err)
+	}
+	if len(prefs.Packages) != 2 || prefs.Packages[1] != "java.time" || prefs.Classes["Element"] != "org.w3c.dom.Element" {
+		t.Fatalf("Unexpected preferences: %+v\n", prefs)
+	}
+}
+
+func TestConfigForFile(t *testing.T) {
+	configHome := t.TempDir()
+	setenv(t, "XDG_CONFIG_HOME", configHome)
+	if err := os.MkdirAll(filepath.Join(configHome, "autoimport"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	global := "layout = intellij\nlayout.ours = *,|,^\nprefer = java.util\nprefer.Date = java.util.Date\nprefer.List = java.util.List\n"
+	if err := os.WriteFile(filepath.Join(configHome, "autoimport", "config"), []byte(global), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	projectDir := t.TempDir()
+	sourceFile := filepath.Join(projectDir, "Main.java")
+
+	// A project configuration file with only preferences does not hide the global layout
+	if err := os.WriteFile(filepath.Join(projectDir, ConfigFilename), []byte("prefer = java.time\nprefer.Date = java.sql.Date\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	layout, err := LayoutForFile(sourceFile, Java)
+	if err != nil || layout == nil || layout.String() != ImportLayouts["intellij"] {
+		t.Fatalf("Expected the global layout, got %v (%v)\n", layout, err)
+	}
+	prefs, err := PreferencesForFile(sourceFile)
+	if err != nil || prefs == nil {
+		t.Fatalf("Could not read the preferences: %v\n", err)
+	}
+	if len(prefs.Packages) != 2 || prefs.Packages[0] != "java.time" || prefs.Packages[1] != "java.util" {
+		t.Errorf("Expected the preferred packages of the project first, got %v\n", prefs.Packages)
+	}
+	if prefs.Classes["Date"] != "java.sql.Date" || prefs.Classes["List"] != "java.util.List" {
+		t.Errorf("Expected the preferred classes of the project to override the global ones, got %v\n", prefs.Classes)
+	}
+
+	// A layout in the project configuration file replaces the global layouts, and can use the global custom layouts
+	if err := os.WriteFile(filepath.Join(projectDir, ConfigFilename), []byte("kotlin_layout = ours\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if layout, err := LayoutForFile(sourceFile, Java); err != nil || layout != nil {
+		t.Fatalf("Expected no layout for Java, got %v (%v)\n", layout, err)
+	}
+	if layout, err := LayoutForFile(sourceFile, Kotlin); err != nil || layout == nil || layout.String() != "*,|,^" {
+		t.Fatalf("Expected the global custom layout for Kotlin, got %v (%v)\n", layout, err)
+	}
+}
@@ -6,14 +6,32 @@ import (
 
 // DeGlob takes a string like "import java.util.*; // ArrayList" and returns "import java.util.ArrayList",
 // for each class/type name that is listed as a comma separated list after "//".
+// If the string has several lines, each line is handled separately. Lines without "*", or without
+// a list of class names after "//", are returned as they are.
 func DeGlob(imports string) []string {
-	if !strings.Contains(imports, ".*") {
-		return []string{imports}
+	var deGlobbed []string
+	for _, line := range strings.Split(imports, "\n") {
+		deGlobbed = append(deGlobbed, deGlobLine(line)...)
 	}
-	fields := strings.SplitN(imports, ".*", 2)
+	return deGlobbed
+}
+
+// deGlobLine returns the import statements for a single line, like "import java.util.*; // ArrayList"
+func deGlobLine(line string) []string {
+	if !strings.Contains(line, ".*") {
+		return []string{line}
+	}
+	fields := strings.SplitN(line, ".*", 2)
 	left := strings.TrimSpace(fields[0])
 	right := strings.TrimSpace(strings.TrimPrefix(fields[1], ";"))
+	if !strings.HasPrefix(right, "//") {
+		// There are no class names to import
+		return []string{line}
+	}
 	right = strings.TrimSpace(strings.TrimPrefix(right, "//"))
+	if right == "" {
+		return []string{line}
+	}
 	var deGlobbed []string
 	for _, className := range strings.Split(right, ",") {
 		deGlobbed = append(deGlobbed, left+"."+strings.TrimSpace(className)+";")
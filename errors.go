@@ -0,0 +1,93 @@
+package autoimport
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNoJava is returned when no Java installation can be found
+	ErrNoJava = errors.New("could not find an installation of Java")
+
+	// ErrNoKotlin is returned when no Kotlin installation can be found
+	ErrNoKotlin = errors.New("could not find an installation of Kotlin")
+)
+
+// ArchiveError is returned when a .jar, .jmod, src.zip or jimage file, or a directory of .class files, can not be read
+type ArchiveError struct {
+	Path string
+	Err  error
+}
+
+func (e *ArchiveError) Error() string {
+	return fmt.Sprintf("could not read archive %s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ArchiveError) Unwrap() error {
+	return e.Err
+}
+
+// SourceError is returned when a ClassSource that is not one of the built-in class sources fails
+type SourceError struct {
+	Source ClassSource
+	Err    error
+}
+
+func (e *SourceError) Error() string {
+	return fmt.Sprintf("could not find the classes in %v: %v", e.Source, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *SourceError) Unwrap() error {
+	return e.Err
+}
+
+// ParseError is returned when Java or Kotlin source code can not be parsed,
+// for instance because of an unterminated comment or string literal
+type ParseError struct {
+	Filename string // the name of the source file, if known
+	Line     int    // the line number, starting at 1
+	Err      error
+}
+
+func (e *ParseError) Error() string {
+	if e.Filename != "" {
+		return fmt.Sprintf("%s:%d: %v", e.Filename, e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// ConfigError is returned when a configuration file can not be parsed,
+// or when it has an import layout that is not valid
+type ConfigError struct {
+	Path string // the path of the configuration file
+	Line int    // the line number, starting at 1, or 0 if the error is not for a specific line
+	Err  error
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+	}
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// withFilename sets the filename of the given error, if it is a *ParseError
+func withFilename(err error, filename string) error {
+	var parseError *ParseError
+	if errors.As(err, &parseError) && parseError.Filename == "" {
+		parseError.Filename = filename
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package autoimport
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArchiveError(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "good.jar"), nil, "java/util/ArrayList.class")
+	brokenJAR := filepath.Join(libPath, "broken.jar")
+	if err := os.WriteFile(brokenJAR, []byte("not a zip file"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A broken archive that is found when searching a directory is skipped
+	ima, err := NewCustom([]string{libPath}, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %v\n", err)
+	}
+	if classPath := ima.ImportPathExact("ArrayList"); classPath != "java.util.ArrayList" {
+		t.Fatalf("Expected java.util.ArrayList, got %q\n", classPath)
+	}
+	archiveErrors := ima.ArchiveErrors()
+	if len(archiveErrors) != 1 || archiveErrors[0].Path != brokenJAR {
+		t.Fatalf("Expected an error for %s, got %v\n", brokenJAR, archiveErrors)
+	}
+
+	// A broken archive that is given directly is an error
+	_, err = NewCustom([]string{brokenJAR}, true)
+	var archiveError *ArchiveError
+	if !errors.As(err, &archiveError) || archiveError.Path != brokenJAR {
+		t.Fatalf("Expected an *ArchiveError for %s, got %v\n", brokenJAR, err)
+	}
+}
+
+func TestFixFileErrors(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "rt.jar"), nil, "java/util/ArrayList.class")
+	opts := Options{JARPaths: []string{libPath}}
+
+	sourceFile := filepath.Join(t.TempDir(), "Main.java")
+	source := "package main;\n\nclass Main {\n    String s = \"unterminated;\n}\n"
+	if err := os.WriteFile(sourceFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	data, changed, err := FixFile(sourceFile, opts, false)
+	var parseError *ParseError
+	if !errors.As(err, &parseError) {
+		t.Fatalf("Expected a *ParseError, got %v\n", err)
+	}
+	if parseError.Filename != sourceFile || parseError.Line != 4 {
+		t.Errorf("Expected an error for %s line 4, got %v\n", sourceFile, parseError)
+	}
+	if changed || string(data) != source {
+		t.Errorf("Expected the original source to be returned unchanged, got %q\n", data)
+	}
+
+	source = "package main;\n\nclass Main {\n    ArrayList<String> a;\n}\n"
+	if err := os.WriteFile(sourceFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, changed, err = FixFile(sourceFile, opts, false); err != nil || !changed {
+		t.Fatalf("Expected the imports to be changed, got changed=%v, err=%v\n", changed, err)
+	}
+
+	source = "package main;\n\nimport java.util.*; // ArrayList\n\nclass Main {\n    ArrayList<String> a;\n}\n"
+	if err := os.WriteFile(sourceFile, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, changed, err = FixFile(sourceFile, opts, false); err != nil || changed {
+		t.Fatalf("Expected no changes to organized imports, got changed=%v, err=%v\n", changed, err)
+	}
+}
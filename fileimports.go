@@ -36,7 +36,7 @@ func (ima *ImportMatcher) FileImports(filename string, verbose bool) (string, er
 	}
 	importBlockBytes, err := ima.ImportBlock(data, verbose)
 	if err != nil {
-		return "", err
+		return "", withFilename(err, filename)
 	}
 	return string(importBlockBytes), nil
 }
@@ -1,7 +1,6 @@
 package autoimport
 
 import (
-	"errors"
 	"path/filepath"
 
 	"github.com/xyproto/env/v2"
@@ -66,5 +65,5 @@ func FindJava() (string, error) {
 	} else if isDir(freeBSDJavaPath) {
 		return freeBSDJavaPath, nil
 	}
-	return "", errors.New("could not find an installation of Java")
+	return "", ErrNoJava
 }
@@ -2,7 +2,6 @@ package autoimport
 
 import (
 	"bytes"
-	"errors"
 	"os"
 	"path/filepath"
 	"strings"
@@ -61,5 +60,5 @@ func FindKotlin() (string, error) {
 	if isDir(kotlinPath) {
 		return kotlinPath, nil
 	}
-	return "", errors.New("could not find an installation of Kotlin")
+	return "", ErrNoKotlin
 }
@@ -0,0 +1,170 @@
+package autoimport
+
+import (
+	"encoding/xml"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xyproto/env/v2"
+)
+
+// FindMavenRepository finds the location of the local Maven repository,
+// typically ~/.m2/repository. The <localRepository> setting in ~/.m2/settings.xml is respected.
+func FindMavenRepository() (string, error) {
+	m2Path := filepath.Join(env.HomeDir(), ".m2")
+	if data, err := os.ReadFile(filepath.Join(m2Path, "settings.xml")); err == nil {
+		var settings struct {
+			LocalRepository string `xml:"localRepository"`
+		}
+		if xml.Unmarshal(data, &settings) == nil && settings.LocalRepository != "" {
+			repoPath := env.ExpandUser(strings.TrimSpace(settings.LocalRepository))
+			if isDir(repoPath) {
+				return repoPath, nil
+			}
+		}
+	}
+	if repoPath := filepath.Join(m2Path, "repository"); isDir(repoPath) {
+		return repoPath, nil
+	}
+	return "", errors.New("could not find a local Maven repository")
+}
+
+// FindGradleCache finds the location of the Gradle dependency cache,
+// typically ~/.gradle/caches/modules-2/files-2.1. $GRADLE_USER_HOME is respected.
+func FindGradleCache() (string, error) {
+	gradleHomePath := env.Str("GRADLE_USER_HOME", filepath.Join(env.HomeDir(), ".gradle"))
+	if cachePath := filepath.Join(gradleHomePath, "caches", "modules-2", "files-2.1"); isDir(cachePath) {
+		return cachePath, nil
+	}
+	return "", errors.New("could not find a Gradle dependency cache")
+}
+
+// FindDependencyJARs returns the newest version of each artifact .jar file
+// that can be found in the local Maven repository and in the Gradle cache.
+func FindDependencyJARs() []string {
+	var JARPaths []string
+	if repoPath, err := FindMavenRepository(); err == nil {
+		JARPaths = append(JARPaths, LatestMavenJARs(repoPath)...)
+	}
+	if cachePath, err := FindGradleCache(); err == nil {
+		JARPaths = append(JARPaths, LatestGradleJARs(cachePath)...)
+	}
+	return JARPaths
+}
+
+// artifactJAR is a .jar file for a specific version of an artifact
+type artifactJAR struct {
+	version string
+	path    string
+}
+
+// latestJARs returns the path to the newest .jar file for each artifact, sorted by path
+func latestJARs(artifacts map[string]artifactJAR) []string {
+	JARPaths := make([]string, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		JARPaths = append(JARPaths, artifact.path)
+	}
+	sort.Strings(JARPaths)
+	return JARPaths
+}
+
+// addArtifactJAR stores the given .jar file for the artifact, if it is newer than the one already stored
+func addArtifactJAR(artifacts map[string]artifactJAR, key, version, path string) {
+	if existing, ok := artifacts[key]; !ok || compareVersions(version, existing.version) > 0 {
+		artifacts[key] = artifactJAR{version, path}
+	}
+}
+
+// LatestMavenJARs returns the newest version of each artifact .jar file in the given Maven repository.
+// The layout is group/path/artifact/version/artifact-version.jar. Jar files with a classifier,
+// like "-sources.jar" or "-javadoc.jar", are skipped.
+func LatestMavenJARs(repoPath string) []string {
+	artifacts := make(map[string]artifactJAR)
+	filepath.Walk(repoPath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".jar" {
+			return nil
+		}
+		versionPath := filepath.Dir(path)
+		artifactPath := filepath.Dir(versionPath)
+		version, artifact := filepath.Base(versionPath), filepath.Base(artifactPath)
+		if filepath.Base(path) != artifact+"-"+version+".jar" {
+			return nil
+		}
+		addArtifactJAR(artifacts, artifactPath, version, path)
+		return nil
+	})
+	return latestJARs(artifacts)
+}
+
+// LatestGradleJARs returns the newest version of each artifact .jar file in the given Gradle cache.
+// The layout is group/artifact/version/hash/artifact-version.jar. Jar files with a classifier,
+// like "-sources.jar" or "-javadoc.jar", are skipped.
+func LatestGradleJARs(cachePath string) []string {
+	artifacts := make(map[string]artifactJAR)
+	filepath.Walk(cachePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(path) != ".jar" {
+			return nil
+		}
+		versionPath := filepath.Dir(filepath.Dir(path))
+		artifactPath := filepath.Dir(versionPath)
+		version, artifact := filepath.Base(versionPath), filepath.Base(artifactPath)
+		if filepath.Base(path) != artifact+"-"+version+".jar" {
+			return nil
+		}
+		addArtifactJAR(artifacts, artifactPath, version, path)
+		return nil
+	})
+	return latestJARs(artifacts)
+}
+
+// compareVersions compares two version strings, like "1.10.0" and "1.9.2-SNAPSHOT".
+// Numeric parts are compared as numbers. A version with a qualifier, like "-SNAPSHOT" or "-RC1",
+// is older than the same version without one. Returns -1, 0 or 1.
+func compareVersions(a, b string) int {
+	splitVersion := func(version string) []string {
+		return strings.FieldsFunc(version, func(r rune) bool {
+			return r == '.' || r == '-' || r == '_' || r == '+'
+		})
+	}
+	aParts, bParts := splitVersion(a), splitVersion(b)
+	for i := 0; i < len(aParts) || i < len(bParts); i++ {
+		if i >= len(aParts) {
+			// a has run out of parts, it is newer if b continues with a qualifier
+			if _, err := strconv.Atoi(bParts[i]); err != nil {
+				return 1
+			}
+			return -1
+		}
+		if i >= len(bParts) {
+			if _, err := strconv.Atoi(aParts[i]); err != nil {
+				return -1
+			}
+			return 1
+		}
+		aNum, aErr := strconv.Atoi(aParts[i])
+		bNum, bErr := strconv.Atoi(bParts[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if aNum != bNum {
+				if aNum < bNum {
+					return -1
+				}
+				return 1
+			}
+		case aErr == nil:
+			// a number is newer than a qualifier
+			return 1
+		case bErr == nil:
+			return -1
+		default:
+			if c := strings.Compare(strings.ToLower(aParts[i]), strings.ToLower(bParts[i])); c != 0 {
+				return c
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package autoimport
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.10.0", "1.9.2", 1},
+		{"1.9.2", "1.10.0", -1},
+		{"2.0", "2.0", 0},
+		{"2.0-SNAPSHOT", "2.0", -1},
+		{"2.0", "2.0-RC1", 1},
+		{"2.0.1", "2.0", 1},
+		{"5.3.27", "6.0.0-M1", -1},
+		{"1.0-alpha", "1.0-beta", -1},
+	}
+	for _, test := range tests {
+		if got := compareVersions(test.a, test.b); got != test.expected {
+			t.Errorf("compareVersions(%q, %q) = %d, expected %d", test.a, test.b, got, test.expected)
+		}
+	}
+}
+
+func TestLatestMavenJARs(t *testing.T) {
+	repoPath := t.TempDir()
+	artifactPath := filepath.Join(repoPath, "org", "springframework", "spring-core")
+	for _, version := range []string{"5.3.27", "6.0.9-SNAPSHOT", "6.0.9"} {
+		writeTestArchive(t, filepath.Join(artifactPath, version, "spring-core-"+version+".jar"), nil, "org/springframework/core/SpringVersion.class")
+		writeTestArchive(t, filepath.Join(artifactPath, version, "spring-core-"+version+"-sources.jar"), nil)
+	}
+	writeTestArchive(t, filepath.Join(repoPath, "junit", "junit", "4.13.2", "junit-4.13.2.jar"), nil, "org/junit/Assert.class")
+
+	JARPaths := LatestMavenJARs(repoPath)
+	expected := []string{
+		filepath.Join(repoPath, "junit", "junit", "4.13.2", "junit-4.13.2.jar"),
+		filepath.Join(artifactPath, "6.0.9", "spring-core-6.0.9.jar"),
+	}
+	if strings.Join(JARPaths, "\n") != strings.Join(expected, "\n") {
+		t.Fatalf("Expected:\n%s\nGot:\n%s", strings.Join(expected, "\n"), strings.Join(JARPaths, "\n"))
+	}
+
+	ima, err := NewCustom(JARPaths, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if foundImport := ima.StarPathExact("SpringVersion"); foundImport != "org.springframework.core.*" {
+		t.Fatalf("Expected org.springframework.core.*, got %q\n", foundImport)
+	}
+}
+
+func TestLatestGradleJARs(t *testing.T) {
+	cachePath := t.TempDir()
+	artifactPath := filepath.Join(cachePath, "org.springframework", "spring-web")
+	writeTestArchive(t, filepath.Join(artifactPath, "5.3.27", "0a1b2c", "spring-web-5.3.27.jar"), nil)
+	writeTestArchive(t, filepath.Join(artifactPath, "6.0.9", "3d4e5f", "spring-web-6.0.9.jar"), nil)
+	writeTestArchive(t, filepath.Join(artifactPath, "6.0.9", "6a7b8c", "spring-web-6.0.9-javadoc.jar"), nil)
+
+	JARPaths := LatestGradleJARs(cachePath)
+	expected := filepath.Join(artifactPath, "6.0.9", "3d4e5f", "spring-web-6.0.9.jar")
+	if len(JARPaths) != 1 || JARPaths[0] != expected {
+		t.Fatalf("Expected %s, got %v\n", expected, JARPaths)
+	}
+}
@@ -1,6 +1,7 @@
 package autoimport
 
 import (
+	"bytes"
 	"os"
 	"strings"
 )
@@ -8,20 +9,39 @@ import (
 // Fix reads in a file and tries to organize the imports.
 // removeExistingImports can be set to true to remove existing imports
 // deGlob can be set to true to try to expand wildcard imports
+// If an error occurs, the original contents of the file are returned together with the error.
+// See also FixFile.
 func Fix(filename string, removeExistingImports, deGlob, verbose bool) ([]byte, error) {
+	opts := Options{RemoveExistingImports: removeExistingImports}
+	if deGlob {
+		opts.ImportStyle = ExplicitImports
+	}
+	data, _, err := FixFile(filename, opts, verbose)
+	return data, err
+}
+
+// FixFile reads in a file and tries to organize the imports, given the options.
+// The language is decided by the file extension, and opts.Language is ignored.
+// Returns the new contents of the file, and true if the contents were changed.
+// If an error occurs, the original contents are returned together with the error,
+// which can be ErrNoJava, ErrNoKotlin, an *ArchiveError, a *SourceError or a *ParseError.
+func FixFile(filename string, opts Options, verbose bool) ([]byte, bool, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
-		return nil, err
+		return nil, false, err
+	}
+	opts.Language = Kotlin
+	if strings.HasSuffix(strings.ToLower(filename), ".java") {
+		opts.Language = Java
 	}
-	onlyJava := strings.HasSuffix(strings.ToLower(filename), ".java")
-	ima, err := New(onlyJava, removeExistingImports, deGlob)
+	ima, err := NewWithOptions(opts)
 	if err != nil {
-		return data, nil // no change
+		return data, false, err
 	}
 	newData, err := ima.FixImports(data, verbose)
 	if err != nil {
-		return data, nil // no change
+		return data, false, withFilename(err, filename)
 	}
 	// with fixed imports
-	return newData, nil
+	return newData, !bytes.Equal(data, newData), nil
 }
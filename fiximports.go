@@ -2,7 +2,6 @@ package autoimport
 
 import (
 	"bytes"
-	"fmt"
 	"sort"
 	"strings"
 )
@@ -12,9 +11,9 @@ var KotlinTypes = []string{
 	"Annotation", "Any", "Array", "Boolean", "Byte", "Char", "CharSequence",
 	"Collection", "Comparable", "Double", "Enum", "Float", "Function", "Int",
 	"IntrinsicConstEvaluation", "Iterable", "Iterator", "List", "ListIterator",
-	"Long", "Map", "Map.Entry", "MutableCollection", "MutableIterable",
+	"Long", "Map", "MutableCollection", "MutableIterable",
 	"MutableIterator", "MutableList", "MutableListIterator", "MutableMap",
-	"MutableMap.MutableEntry", "MutableSet", "Nothing", "Number", "Pair",
+	"MutableSet", "Nothing", "Number", "Pair",
 	"PlatformDependent", "PureReifiable", "Runnable", "Set", "Short", "String",
 	"Throwable", "Triple", "Unit", "UByte", "UInt", "ULong", "UShort",
 }
@@ -35,109 +34,229 @@ func ForEachLineInData(data []byte, process func(string, string)) {
 	})
 }
 
-// ImportBlock generates "import" lines for the given Java or Kotlin source code
-func (ima *ImportMatcher) ImportBlock(data []byte, verbose bool) ([]byte, error) {
-	importMap := make(map[string]string) // from import path to comment (including "// ")
-	skipWords := []string{"package", "public", "private", "protected"}
-	var typeAliases []string
-	var kotlinClassDefs []string
-	var inComment bool
-	ForEachLineInData(data, func(line, trimmedLine string) {
-		if strings.HasPrefix(trimmedLine, "//") {
-			return // continue
+// resolvedType is a type name that is used in the source code, together with the import that provides it
+type resolvedType struct {
+	name       string
+	classPath  string   // the class path, like "java.util.List"
+	starPath   string   // the import path, like "java.util.*"
+	line       int      // the line where the type name is first used
+	shortened  []token  // the qualified names, like "Map.Entry", that are shortened to the name of this nested class
+	candidates []string // all the classes with this name, best ranked first, if the name is ambiguous
+}
+
+// nestedClass returns the class path and the qualified name of the nested class that the given qualified name
+// starts with, like "android.os.Build.VERSION" and "Build.VERSION" for "Build.VERSION.SDK_INT".
+// Returns empty strings if no such nested class is known.
+func (ima *ImportMatcher) nestedClass(qualifiedName string) (string, string) {
+	for name := qualifiedName; strings.Contains(name, "."); name = name[:strings.LastIndex(name, ".")] {
+		if classPath := ima.ImportPathExact(name); classPath != "" {
+			return classPath, name
 		}
-		for _, skipWord := range skipWords {
-			if strings.HasPrefix(trimmedLine, skipWord) {
-				return // continue
-			}
+	}
+	return "", ""
+}
+
+// canShorten checks if the nested class with the given class path, like "java.util.Map.Entry", can be used
+// with just its own name, like "Entry", without clashing with other types in the given source code
+func (ima *ImportMatcher) canShorten(src *parsedSource, classPath string) bool {
+	name := classNameOf(classPath)
+	if hasS(src.declaredTypes, name) || (!ima.onlyJava && hasS(KotlinTypes, name)) {
+		return false
+	}
+	for _, typeName := range src.typeNames {
+		if typeName.text == name {
+			return false
 		}
-		// Pick up and store type aliases, so that these are not imported
-		if !ima.onlyJava && strings.HasPrefix(trimmedLine, "typealias ") {
-			fields := strings.Fields(trimmedLine)
-			if len(fields) > 2 {
-				typeAliases = append(typeAliases, strings.TrimSpace(fields[1]))
-			}
-			return // continue
-		}
-		// Pick up and store class definitions within the same file, so that these are not imported
-		if !ima.onlyJava && strings.HasPrefix(trimmedLine, "class ") || strings.Contains(trimmedLine, " class ") {
-			fields := strings.Fields(trimmedLine)
-			for i := range fields {
-				if i > 0 && strings.TrimSpace(fields[i-1]) == "class" {
-					kotlinClassDefs = append(kotlinClassDefs, strings.TrimSpace(fields[i]))
-				}
-			}
-			return // continue
+	}
+	for _, stmt := range src.imports {
+		if (stmt.alias == name || (stmt.alias == "" && classNameOf(stmt.path) == name)) && stmt.path != classPath {
+			return false
 		}
-		if !inComment && strings.Contains(trimmedLine, "/*") && !strings.Contains(trimmedLine, "*/") {
-			inComment = true
-		} else if inComment && strings.Contains(trimmedLine, "*/") && !strings.Contains(trimmedLine, "/*") {
-			inComment = false
+	}
+	return true
+}
+
+// resolveTypeNames finds the imports that are needed for the type names that are used in the given source code.
+// Type names that are declared in the same file, built-in Kotlin types, classes in java.lang and
+// classes in the same package are skipped. If a type name is ambiguous, the class that is already imported
+// or preferred is used, and otherwise the best ranked class. When a nested class is used together with the name of the outer
+// class, like "Map.Entry", the outer class that contains the nested class is imported. If shorten is true,
+// the nested class is imported instead, and the qualified names are returned with it, so that they can be
+// shortened to just the name of the nested class, like "Entry".
+func (ima *ImportMatcher) resolveTypeNames(src *parsedSource, shorten bool) []resolvedType {
+	var resolved []resolvedType
+	outerClasses := make(map[string]string) // from the name of an outer class to its class path
+	nested := make(map[string]int)          // from the name of a nested class to its position in resolved
+	shortenedOuter := make(map[int]bool)    // the positions of the outer class names that are shortened away
+	for _, qualifiedName := range src.qualifiedNames {
+		outerName := qualifiedName.text[:strings.Index(qualifiedName.text, ".")]
+		if hasS(src.declaredTypes, outerName) || (!ima.onlyJava && hasS(KotlinTypes, outerName)) {
+			continue
 		}
-		if inComment {
-			return // continue
+		classPath, nestedName := ima.nestedClass(qualifiedName.text)
+		if classPath == "" {
+			continue
 		}
-		words := strings.Fields(trimmedLine)
-		for _, word := range words {
-			if strings.Contains(word, "(") {
-				fields := strings.SplitN(word, "(", 2)
-				word = strings.TrimSpace(fields[0])
-			}
-			if strings.Contains(word, "<") {
-				fields := strings.SplitN(word, "<", 2)
-				word = strings.TrimSpace(fields[0])
-			}
-			word = strings.TrimPrefix(word, "@") // Also handle attributes / decorators
-			if word == "" {
-				continue
-			}
-			if !ima.onlyJava && hasS(typeAliases, word) {
-				// Do not import Kotlin classes with the same names as type imports
-				continue
-			}
-			if !ima.onlyJava && hasS(kotlinClassDefs, word) {
-				// Do not import Kotlin classes that are defined in the same file
-				continue
-			}
-			if !ima.onlyJava && hasS(KotlinTypes, word) {
-				// Do not import anything for Kotlin types like List or String
-				continue
-			}
-			foundImport := ima.StarPathExact(word)
-			if foundImport == "java.lang.*" {
-				continue
-			}
-			if strings.HasPrefix(foundImport, "java.desktop.java.") {
-				foundImport = strings.TrimPrefix(foundImport, "java.desktop.")
-			}
-			if foundImport != "" {
-				key := "import " + foundImport + "; // "
-				value := word
-				if verbose {
-					fmt.Printf("%s\t->\t%s%s\n", word, key, value)
-				}
-				if v, found := importMap[key]; found {
-					if !strings.Contains(v, value) {
-						newValues := v + ", " + value
-						fields := strings.Split(newValues, ", ")
-						sort.Strings(fields)
-						importMap[key] = strings.Join(fields, ", ")
-					}
-				} else {
-					importMap[key] = value
-				}
-			}
+		name := classNameOf(classPath)
+		if i, ok := nested[name]; ok && resolved[i].classPath == classPath {
+			resolved[i].shortened = append(resolved[i].shortened, token{text: nestedName, line: qualifiedName.line, offset: qualifiedName.offset})
+			shortenedOuter[qualifiedName.offset] = true
+			continue
+		}
+		if _, ok := nested[name]; !ok && shorten && ima.canShorten(src, classPath) {
+			nested[name] = len(resolved)
+			resolved = append(resolved, resolvedType{
+				name:      name,
+				classPath: classPath,
+				starPath:  starPathOf(classPath),
+				line:      qualifiedName.line,
+				shortened: []token{{text: nestedName, line: qualifiedName.line, offset: qualifiedName.offset}},
+			})
+			shortenedOuter[qualifiedName.offset] = true
+			continue
+		}
+		if _, ok := outerClasses[outerName]; !ok {
+			outerClasses[outerName] = strings.TrimSuffix(classPath, nestedName) + outerName
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, typeName := range src.typeNames {
+		word := typeName.text
+		if seen[word] || shortenedOuter[typeName.offset] {
+			continue
+		}
+		seen[word] = true
+		if hasS(src.declaredTypes, word) {
+			// Do not import classes with the same names as classes or type aliases defined in the same file
+			continue
+		}
+		if !ima.onlyJava && hasS(KotlinTypes, word) {
+			// Do not import anything for Kotlin types like List or String
+			continue
+		}
+		if _, ok := ima.Info(src.packageName + "." + word); ok && src.packageName != "" {
+			// A class in the same package does not need to be imported, even if it is not public
+			continue
+		}
+		classPath, ok := outerClasses[word]
+		var candidates []string
+		if !ok {
+			classPath, candidates = ima.chooseClass(src, word)
 		}
+		if classPath == "" {
+			continue
+		}
+		foundImport := starPathOf(classPath)
+		if foundImport == "java.lang.*" || (src.packageName != "" && foundImport == src.packageName+".*") {
+			continue
+		}
+		resolved = append(resolved, resolvedType{name: word, classPath: classPath, starPath: foundImport, line: typeName.line, candidates: candidates})
+	}
+	sort.SliceStable(resolved, func(i, j int) bool {
+		return resolved[i].line < resolved[j].line
 	})
+	return resolved
+}
+
+// shortenNestedNames replaces the given qualified names of nested classes in the source code, like "Map.Entry",
+// with just the names of the nested classes, like "Entry"
+func shortenNestedNames(data []byte, resolved []resolvedType) []byte {
+	var qualifiedNames []token
+	for _, resolvedType := range resolved {
+		qualifiedNames = append(qualifiedNames, resolvedType.shortened...)
+	}
+	if len(qualifiedNames) == 0 {
+		return data
+	}
+	sort.Slice(qualifiedNames, func(i, j int) bool {
+		return qualifiedNames[i].offset > qualifiedNames[j].offset
+	})
+	shortened := append([]byte{}, data...)
+	for _, qualifiedName := range qualifiedNames {
+		end := qualifiedName.offset + len(qualifiedName.text)
+		if end > len(shortened) || string(shortened[qualifiedName.offset:end]) != qualifiedName.text {
+			continue
+		}
+		outerLength := len(qualifiedName.text) - len(classNameOf(qualifiedName.text))
+		shortened = append(shortened[:qualifiedName.offset], shortened[qualifiedName.offset+outerLength:]...)
+	}
+	return shortened
+}
+
+// withoutExplicitImports removes the class names that are already imported by the given explicit imports,
+// like "java.util.ArrayList", from the comment of a generated wildcard import, like
+// "import java.util.*; // ArrayList, HashMap". Returns an empty string if all the class names are already imported.
+// Wildcard imports without a comment are returned as they are, since it is not known which classes they are for.
+func withoutExplicitImports(importLine string, explicitImports map[string]bool) string {
+	fields := strings.SplitN(importLine, "; // ", 2)
+	if len(fields) != 2 || !strings.HasSuffix(fields[0], ".*") || strings.HasPrefix(fields[0], "import static ") {
+		return importLine
+	}
+	packagePrefix := strings.TrimSuffix(strings.TrimPrefix(fields[0], "import "), "*")
+	var names []string
+	for _, name := range strings.Split(fields[1], ", ") {
+		if !explicitImports[packagePrefix+name] {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return fields[0] + "; // " + strings.Join(names, ", ")
+}
+
+// ImportBlock generates "import" lines for the given Java or Kotlin source code.
+// Static members that are called without a qualifier get "import static" lines in Java, and
+// Kotlin top-level functions and properties get explicit imports in Kotlin.
+// If NestedImports is set, nested classes that are used like "Map.Entry" are imported instead of their
+// outer classes, but only FixImports shortens the qualified names in the source code.
+func (ima *ImportMatcher) ImportBlock(data []byte, verbose bool) ([]byte, error) {
+	importMap := make(map[string]string) // from import path to comment (including "// ")
+	src, err := parseSource(data, !ima.onlyJava)
+	if err != nil {
+		return nil, err
+	}
+	for _, resolved := range ima.resolveTypeNames(src, ima.NestedImports) {
+		key := "import " + resolved.starPath + "; // "
+		value := resolved.name
+		if verbose {
+			ima.logf("%s\t->\t%s%s", resolved.name, key, value)
+			if len(resolved.candidates) > 0 {
+				ima.logf("%s is ambiguous: %s", resolved.name, strings.Join(resolved.candidates, ", "))
+			}
+		}
+		if v, found := importMap[key]; found {
+			fields := append(strings.Split(v, ", "), value)
+			sort.Strings(fields)
+			importMap[key] = strings.Join(fields, ", ")
+		} else {
+			importMap[key] = value
+		}
+	}
 	var importLines []string
 	var importLine string
 	for k, v := range importMap {
 		importLine = k + v
+		if ima.commentStyle == NoComments && !ima.DeGlob {
+			// DeGlob needs the comments, so they are only left out if DeGlob is not used
+			importLine = strings.TrimSuffix(k, " // ")
+		}
 		if importLine != "" {
 			importLines = append(importLines, importLine)
 		}
 	}
-	sort.Strings(importLines)
+	for _, member := range ima.resolveMembers(src) {
+		if member.imported && !ima.removeExistingImports {
+			// The existing import is kept
+			continue
+		}
+		if verbose {
+			ima.logf("%s\t->\t%s", member.name, member.importLine())
+		}
+		importLines = append(importLines, member.importLine())
+	}
+	importLines = ima.orderImportLines(importLines, src.packageName)
 	importBlock := strings.Join(importLines, "\n")
 	return []byte(importBlock), nil
 }
@@ -145,20 +264,43 @@ func (ima *ImportMatcher) ImportBlock(data []byte, verbose bool) ([]byte, error)
 // FixImports generates sorted "import" lines for a .java or .kotlin file
 // (the ImportMatcher should be configured to be either for Java or Kotlin as well).
 // The existing imports (if any) are the replaced with the generated imports.
+// If a Layout is set, the imports are grouped and ordered according to it.
 func (ima *ImportMatcher) FixImports(data []byte, verbose bool) ([]byte, error) {
+	src, err := parseSource(data, !ima.onlyJava)
+	if err != nil {
+		return nil, err
+	}
 	importBlockBytes, err := ima.ImportBlock(data, verbose)
 	if err != nil {
 		return nil, err
 	}
+	if ima.NestedImports {
+		// The qualified names of the imported nested classes are shortened, like "Map.Entry" to "Entry"
+		data = shortenNestedNames(data, ima.resolveTypeNames(src, true))
+	}
 
 	// Imports are found, now modify the given source code and return it
 
 	hasImports := bytes.Contains(data, []byte("\nimport "))
 
+	importLines := strings.Split(string(importBlockBytes), "\n")
+
 	if hasImports && !ima.removeExistingImports {
+		var unusedLines map[int]bool
+		if ima.RemoveUnusedImports {
+			unusedLines = ima.unusedImportLines(src)
+		}
 		importMap := make(map[string]string)
+		lineNumber := 0
 		ForEachLineInData(data, func(line, trimmedLine string) {
+			lineNumber++
 			if strings.HasPrefix(trimmedLine, "import ") {
+				if unusedLines[lineNumber] {
+					if verbose {
+						ima.logf("Removed unused import: %s", trimmedLine)
+					}
+					return // continue
+				}
 				key := trimmedLine
 				if strings.Contains(key, ";") {
 					fields := strings.SplitN(key, ";", 2)
@@ -168,13 +310,19 @@ func (ima *ImportMatcher) FixImports(data []byte, verbose bool) ([]byte, error)
 			}
 		})
 		if verbose {
-			fmt.Println("Existing imports:")
+			ima.logf("Existing imports:")
 			for _, v := range importMap {
-				fmt.Println(v)
+				ima.logf("%s", v)
+			}
+		}
+		explicitImports := make(map[string]bool)
+		for _, stmt := range src.imports {
+			if !stmt.static && !strings.HasSuffix(stmt.path, ".*") && !unusedLines[stmt.line] {
+				explicitImports[stmt.path] = true
 			}
 		}
 		ForEachLineInData(importBlockBytes, func(line, trimmedLine string) {
-			if trimmedLine == "" {
+			if trimmedLine = withoutExplicitImports(trimmedLine, explicitImports); trimmedLine == "" {
 				return // continue
 			}
 			key := trimmedLine
@@ -185,35 +333,48 @@ func (ima *ImportMatcher) FixImports(data []byte, verbose bool) ([]byte, error)
 			importMap[key] = trimmedLine
 		})
 		if verbose {
-			fmt.Println("Existing and new imports:")
+			ima.logf("Existing and new imports:")
 			for _, v := range importMap {
-				fmt.Println(v)
+				ima.logf("%s", v)
 			}
 		}
-		var importLines []string
+		// We now have new import lines that keep the old imports, but not duplicates
+		importLines = make([]string, 0, len(importMap))
 		for _, trimmedLine := range importMap {
 			importLines = append(importLines, trimmedLine)
 		}
-		sort.Strings(importLines)
-		if verbose {
-			fmt.Println("Existing and new imports, sorted:")
-			for _, importLine := range importLines {
-				fmt.Println(importLine)
+	}
+
+	if ima.DeGlob {
+		// The existing imports may already import some of the classes
+		seen := make(map[string]bool)
+		var deGlobbed []string
+		for _, importLine := range DeGlob(strings.Join(importLines, "\n")) {
+			key := strings.TrimSuffix(strings.TrimSpace(importLine), ";")
+			if !seen[key] {
+				seen[key] = true
+				deGlobbed = append(deGlobbed, importLine)
 			}
 		}
-		// We now have a new import block that keeps the old imports, but not duplicates
-		importBlockBytes = []byte(strings.Join(importLines, "\n"))
+		importLines = deGlobbed
 	}
 
-	if ima.DeGlob {
-		importBlockBytes = []byte(strings.Join(DeGlob(string(importBlockBytes)), "\n"))
+	importLines = ima.orderImportLines(importLines, src.packageName)
+	if verbose && hasImports && !ima.removeExistingImports {
+		ima.logf("Existing and new imports, sorted:")
+		for _, importLine := range importLines {
+			ima.logf("%s", importLine)
+		}
 	}
+	importBlockBytes = []byte(strings.Join(importLines, "\n"))
 
 	// Now replace/insert the newly organized import statements
 
 	var (
 		sb               strings.Builder
 		importsDone      bool
+		inImports        bool // true while skipping the existing import lines and the blank lines between them
+		skippedBlankLine bool
 		ignoreBlankLines int
 		blankLineCount   int
 	)
@@ -236,26 +397,36 @@ func (ima *ImportMatcher) FixImports(data []byte, verbose bool) ([]byte, error)
 			ignoreBlankLines = 0
 		}
 
+		if inImports {
+			if trimmedLine == "" || strings.HasPrefix(trimmedLine, "import ") {
+				skippedBlankLine = skippedBlankLine || trimmedLine == ""
+				return // continue
+			}
+			inImports = false
+			// Keep the blank line between the imports and the rest of the code
+			if skippedBlankLine && len(importBlockBytes) > 0 {
+				sb.WriteString("\n")
+			}
+		}
+
 		if hasImports && strings.HasPrefix(trimmedLine, "import ") {
 			if !importsDone {
-				sb.Write(importBlockBytes)
-				sb.WriteString("\n")
-				importsDone = true
-				ignoreBlankLines = 2
-				if !bytes.HasSuffix(importBlockBytes, []byte{'\n', '\n'}) {
-					ignoreBlankLines = 0
+				if len(importBlockBytes) > 0 {
+					sb.Write(importBlockBytes)
+					sb.WriteString("\n")
 				}
+				importsDone = true
+				inImports = true
 			} // else ignore this "import" line
 		} else if !hasImports && strings.HasPrefix(trimmedLine, "package ") {
 			sb.WriteString(line + "\n")
-			if ima.DeGlob {
-				sb.WriteString("\n")
-			}
-			sb.Write(importBlockBytes)
-			if !bytes.HasSuffix(importBlockBytes, []byte{'\n', '\n'}) {
+			if len(importBlockBytes) > 0 {
+				// One blank line before and after the imports
 				sb.WriteString("\n")
+				sb.Write(importBlockBytes)
+				sb.WriteString("\n\n")
+				ignoreBlankLines = 1
 			}
-			sb.WriteString("\n")
 		} else {
 			sb.WriteString(line + "\n")
 		}
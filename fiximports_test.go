@@ -108,9 +108,9 @@ public class SimpleJoglApp extends JFrame {
 }
 `,
 			expected: `
-import java.awt.*; // Dimension
+import java.awt.*; // BorderLayout, Component, Dimension, Toolkit
 import java.awt.event.*;
-import javax.swing.*;
+import javax.swing.*; // JFrame, SwingUtilities
 import net.java.games.jogl.*;
 
 
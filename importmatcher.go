@@ -4,36 +4,91 @@ package autoimport
 import (
 	"archive/zip"
 	"errors"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"unicode"
 )
 
 // ImportMatcher is a struct that contains a list of JAR file paths,
-// and a lookup map from class names to class paths, which is populated
-// when New or NewCustom is called.
+// and a lookup map from class names to all matching class paths, which is populated
+// when New, NewCustom or NewWithOptions is called.
 type ImportMatcher struct {
-	classMap              map[string]string // map from class name to class path. Shortest class path "wins".
-	JARPaths              []string          // list of paths to examine for .jar files
-	mut                   sync.RWMutex      // mutex for protecting the map
-	onlyJava              bool              // only Java, or Kotlin too?
-	removeExistingImports bool              // keep existing imports (but also avoid duplicates)
-	DeGlob                bool              // generate import statements without "*"
+	classMap              map[string][]string    // map from class name to all class paths, best ranked first
+	classInfo             map[string]ClassInfo   // map from class path to information about where the class was found
+	memberMap             map[string][]memberRef // map from member name to all classes with such a static member, best ranked first
+	JARPaths              []string               // list of paths to examine for .jar files
+	SourcePaths           []string               // list of project source trees to examine for .java and .kt files
+	Sources               []ClassSource          // where the classes are searched for, including the JARPaths and SourcePaths
+	mut                   sync.RWMutex           // mutex for protecting the map
+	onlyJava              bool                   // only Java, or Kotlin too?
+	kotlinPath            string                 // the Kotlin installation, if it has been searched for classes
+	shared                *sharedIndex           // the class index with the Kotlin installation, that is shared by ForLanguage
+	release               int                    // the target Java release for multi-release jars, or 0 for all releases
+	removeExistingImports bool                   // keep existing imports (but also avoid duplicates)
+	DeGlob                bool                   // generate import statements without "*"
+	RemoveUnusedImports   bool                   // remove existing imports that are not used, when keeping existing imports
+	NestedImports         bool                   // import nested classes, like "java.util.Map.Entry", instead of their outer classes
+	Layout                *ImportLayout          // how the imports are grouped and ordered. If nil, they are just sorted.
+	Preferences           *Preferences           // which classes to import when class names are ambiguous, if any
+	Ranker                Ranker                 // ranks the classes with the same name for a source file. If nil, DefaultRanker is used.
+	projectImports        map[string]int         // map from package to the number of project source files that import from it
+	dependencyArchives    map[string]bool        // the archives of the dependencies that are declared by the project
+	commentStyle          CommentStyle           // which comments to add after generated wildcard imports
+	logger                *log.Logger            // where verbose output and problems are written, if not stdout and stderr
+	cache                 *classCache            // on-disk cache of the classes found in each archive
+	archiveErrors         []*ArchiveError        // archives that could not be read
+}
+
+// ClassInfo contains information about a class that has been found,
+// and about where it was found
+type ClassInfo struct {
+	Path    string // the class path, like "java.util.List"
+	Module  string // the name of the module the class belongs to, like "java.base", if known
+	Archive string // the path to the .jar, .jmod, src.zip or jimage file (or source file) the class was found in
+	Project bool   // true if the class was found in the source tree of the project
+
+	Kind           ClassKind // the kind of type, if the class file has been read
+	PackagePrivate bool      // true if the class is not public, and can only be used from the same package
+	Nested         string    // the qualified name within the package of a nested class, like "Map.Entry"
+	Deprecated     bool      // true if the class, or the module it belongs to, is deprecated
+	Release        int       // the Java release of a versioned entry in a multi-release jar, like 11 for "META-INF/versions/11/", or 0
+
+	Members      []MemberInfo // the public static members, or the top-level functions and properties of a Kotlin file facade
+	KotlinFacade bool         // true if the class contains the top-level functions and properties of a Kotlin file, like "FileKt"
+}
+
+// memberRef is a class with a static member, or a Kotlin file facade with a top-level function or property
+type memberRef struct {
+	classPath string
+	property  bool
+	topLevel  bool // a member of a Kotlin file facade, that is imported from the package instead of from the class
+}
+
+// importPath returns the import path of the member with the given name, like "org.junit.Assert.assertEquals".
+// For Kotlin, top-level functions and properties are imported from the package, like "kotlinx.coroutines.runBlocking".
+func (ref memberRef) importPath(name string, kotlin bool) string {
+	if kotlin && ref.topLevel {
+		return strings.TrimSuffix(starPathOf(ref.classPath), "*") + name
+	}
+	return ref.classPath + "." + name
 }
 
 // New creates a new ImportMatcher. If onlyJava is false, /usr/share/kotlin/lib will be added to the .jar file search path.
 // The first (optional) bool should be set to true if only Java should be considered, and not Kotlin.
 // The second (optional) bool should be set to true if the import organizer should always start out with removing existing imports.
 // The third (optional) bool should be set to true if the generated imports should be exact intead of with a glob ("*").
+// Use NewWithOptions(Options{Dependencies: true}) to also search the local Maven repository and the Gradle cache.
 func New(settings ...bool) (*ImportMatcher, error) {
+	return NewWithOptions(settingsOptions(settings...))
+}
 
-	var onlyJava bool
-	if len(settings) > 0 {
-		onlyJava = settings[0]
-	}
-
+// installationPaths returns the paths to the Java installation and,
+// if onlyJava is false, the Kotlin installation
+func installationPaths(onlyJava bool) ([]string, error) {
 	javaHomePath, err := FindJava()
 	if err != nil {
 		return nil, err
@@ -46,8 +101,7 @@ func New(settings ...bool) (*ImportMatcher, error) {
 		}
 		JARSearchPaths = append(JARSearchPaths, kotlinPath)
 	}
-
-	return NewCustom(JARSearchPaths, settings...)
+	return JARSearchPaths, nil
 }
 
 // addDir adds a directory to the current slice of paths to search for .jar files
@@ -58,75 +112,146 @@ func (ima *ImportMatcher) addDir(path string) {
 	ima.JARPaths = append(ima.JARPaths, path)
 }
 
-// NewCustom creates a new ImportMatcher, given a slice of paths to search for .jar files
+// resolveJARPath follows the given path if it is a symlink, and returns the path to search for
+// .jar files, and true if it is a directory. An empty path is returned if it does not exist.
+func resolveJARPath(path string) (string, bool) {
+	if isSymlink(path) {
+		// follow the symlink, once
+		path = followSymlink(path)
+		// if the path is a directory, collect it
+		if isDir(path) {
+			return path, true
+		}
+		// follow the symlink, repeatedly
+		for isSymlink(path) {
+			path = followSymlink(path)
+		}
+	}
+	if isDir(path) {
+		return path, true
+	}
+	if exists(path) {
+		return path, false
+	}
+	return "", false
+}
+
+// NewCustom creates a new ImportMatcher, given a slice of paths to search for .jar files.
+// The paths can also be paths to .jar files.
 // The first (optional) bool should be set to true if only Java should be considered, and not Kotlin.
 // The second (optional) bool should be set to true if the import organizer should always start out with removing existing imports.
 // The third (optional) bool should be set to true if the generated imports should be exact intead of with a glob ("*").
+// See also NewWithOptions.
 func NewCustom(JARPaths []string, settings ...bool) (*ImportMatcher, error) {
-	var ima ImportMatcher
-
-	if len(settings) > 0 {
-		ima.onlyJava = settings[0]
+	if len(JARPaths) == 0 {
+		return nil, errors.New("no paths to search for JAR files")
 	}
+	opts := settingsOptions(settings...)
+	opts.JARPaths = JARPaths
+	opts.Dependencies = false
+	return NewWithOptions(opts)
+}
 
-	if len(settings) > 1 {
-		ima.removeExistingImports = settings[1]
+// index searches the given paths for .jar files (and the other supported archives),
+// the given project source trees for .java and .kt files and the given class sources,
+// and populates the lookup maps
+func (ima *ImportMatcher) index(JARPaths, sourcePaths []string, sources []ClassSource) error {
+	ima.JARPaths = make([]string, 0)
+	for _, path := range JARPaths {
+		if path, dir := resolveJARPath(path); dir {
+			ima.addDir(path)
+		} else if path != "" {
+			// a single .jar file
+			ima.JARPaths = append(ima.JARPaths, path)
+		}
 	}
 
-	if len(settings) > 2 {
-		ima.DeGlob = settings[2]
+	if len(ima.JARPaths) == 0 && len(sources) == 0 {
+		return errors.New("no paths to search for JAR files")
 	}
 
-	ima.JARPaths = make([]string, 0)
-	for _, path := range JARPaths {
-		if isSymlink(path) {
-			// follow the symlink, once
-			path = followSymlink(path)
-			// if the path is a directory, collect it
-			if isDir(path) {
-				ima.addDir(path)
-				continue
-			}
-			// follow the symlink, repeatedly
-			for isSymlink(path) {
-				path = followSymlink(path)
-			}
-			// if the path is a directory, collect it
-			if isDir(path) {
-				ima.addDir(path)
-			}
-		} else if isDir(path) {
-			ima.addDir(path)
+	ima.SourcePaths = make([]string, 0)
+	for _, path := range sourcePaths {
+		if isDir(path) {
+			ima.SourcePaths = append(ima.SourcePaths, path)
 		}
 	}
 
-	if len(ima.JARPaths) == 0 {
-		return nil, errors.New("no paths to search for JAR files")
+	ima.Sources = make([]ClassSource, 0, len(ima.JARPaths)+len(ima.SourcePaths)+len(sources))
+	for _, path := range ima.JARPaths {
+		if isDir(path) {
+			ima.Sources = append(ima.Sources, JARDirSource{Path: path})
+		} else {
+			ima.Sources = append(ima.Sources, ArchiveSource{Path: path})
+		}
 	}
+	for _, path := range ima.SourcePaths {
+		ima.Sources = append(ima.Sources, SourceTreeSource{Path: path})
+	}
+	ima.Sources = append(ima.Sources, sources...)
 
-	ima.classMap = make(map[string]string)
+	ima.classMap = make(map[string][]string)
+	ima.classInfo = make(map[string]ClassInfo)
+	ima.memberMap = make(map[string][]memberRef)
+	ima.projectImports = make(map[string]int)
 
-	found := make(chan string)
+	found := make(chan ClassInfo)
 	done := make(chan bool)
 
-	go ima.produceClasses(found)
+	sourceErrors := make([]error, len(ima.Sources))
+	go ima.produceClasses(found, sourceErrors)
 	go ima.consumeClasses(found, done)
 	<-done
 
-	return &ima, nil
+	// The cache is only an optimization, so errors when saving it are ignored
+	_ = ima.cache.save()
+
+	if ima.kotlinPath != "" {
+		ima.shared = &sharedIndex{classMap: ima.classMap, classInfo: ima.classInfo, memberMap: ima.memberMap}
+	}
+
+	// Archives that are given directly, and the given class sources, should be readable
+	for _, err := range sourceErrors {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// ClassMap returns the mapping from class names to class paths
+// ClassMap returns the mapping from class names to the best ranked class paths
 func (ima *ImportMatcher) ClassMap() map[string]string {
-	return ima.classMap
+	ima.mut.RLock()
+	defer ima.mut.RUnlock()
+	classMap := make(map[string]string, len(ima.classMap))
+	for className, classPaths := range ima.classMap {
+		classMap[className] = classPaths[0]
+	}
+	return classMap
+}
+
+// Info returns information about where the given class path, like "java.util.List", was found
+func (ima *ImportMatcher) Info(classPath string) (ClassInfo, bool) {
+	ima.mut.RLock()
+	defer ima.mut.RUnlock()
+	info, ok := ima.classInfo[classPath]
+	return info, ok
+}
+
+// Module returns the name of the module that contains the given class path,
+// like "java.base" for "java.util.List". Returns an empty string if the module is not known.
+func (ima *ImportMatcher) Module(classPath string) string {
+	info, _ := ima.Info(classPath)
+	return info.Module
 }
 
 // readSOURCE returns a list of classes within the given src.zip file,
 // for instance "some.package.name.SomeClass"
-func (ima *ImportMatcher) readSOURCE(filePath string, found chan string) {
+func (ima *ImportMatcher) readSOURCE(filePath string, found chan ClassInfo) error {
 	readCloser, err := zip.OpenReader(filePath)
 	if err != nil {
-		return
+		return err
 	}
 	defer readCloser.Close()
 
@@ -134,80 +259,141 @@ func (ima *ImportMatcher) readSOURCE(filePath string, found chan string) {
 		fileName := f.Name
 		if strings.HasSuffix(fileName, ".java") || strings.HasSuffix(fileName, ".JAVA") {
 
-			// The class name is derived from the .java path within the src.zip file
+			// The class name is derived from the .java path within the src.zip file.
+			// For JDK 9 and later, the first directory is the name of the module, like "java.base/".
+
+			var moduleName string
+			if pos := strings.Index(fileName, "/"); pos > 0 && strings.Contains(fileName[:pos], ".") {
+				moduleName = fileName[:pos]
+				fileName = fileName[pos+1:]
+			}
 
 			className := strings.TrimSuffix(strings.TrimSuffix(fileName, ".java"), ".JAVA")
 			className = strings.ReplaceAll(className, "/", ".")
-			className = strings.TrimPrefix(className, "java.base.")
-			className = strings.TrimPrefix(className, "jdk.internal.")
-			if className == "" {
+			if className == "" || allLower(className) || isDescriptor(className) {
 				continue
 			}
 
-			// Filter out class names that are only lowercase (and '.')
-			allLower := true
-			for _, r := range className {
-				if !unicode.IsLower(r) && r != '.' {
-					allLower = false
-				}
-			}
-			if allLower {
-				continue
-			}
+			found <- ClassInfo{Path: className, Module: moduleName, Archive: filePath}
+		}
+	}
+	return nil
+}
 
-			found <- className
+// allLower checks if the given class name only consists of lowercase letters (and '.')
+func allLower(className string) bool {
+	for _, r := range className {
+		if !unicode.IsLower(r) && r != '.' {
+			return false
 		}
 	}
+	return true
 }
 
-// readJAR returns a list of classes within the given .jar file,
-// for instance "some.package.name.SomeClass"
-func (ima *ImportMatcher) readJAR(filePath string, found chan string) {
+// classPathFromEntry derives a class path like "some.package.name.SomeClass"
+// from a .class file name within an archive, like "some/package/name/SomeClass$1.class".
+// Returns an empty string if the entry is not an importable class.
+func classPathFromEntry(fileName string) string {
+	if !strings.HasSuffix(fileName, ".class") && !strings.HasSuffix(fileName, ".CLASS") {
+		return ""
+	}
+	className := strings.TrimSuffix(strings.TrimSuffix(fileName, ".class"), ".CLASS")
+	className = strings.ReplaceAll(className, "/", ".")
+	className = strings.TrimSuffix(className, "$1")
+	className = strings.TrimSuffix(className, "$1")
+	if pos := strings.Index(className, "$"); pos >= 0 {
+		className = className[:pos]
+	}
+	if className == "" || allLower(className) || isDescriptor(className) {
+		return ""
+	}
+	return className
+}
+
+// isDescriptor checks if the given class path is a module descriptor or a package descriptor,
+// like "module-info" or "org.example.package-info", which are not classes that can be imported
+func isDescriptor(classPath string) bool {
+	className := classNameOf(classPath)
+	return className == "module-info" || className == "package-info"
+}
+
+// readJAR returns a list of classes within the given .jar (or .war) file,
+// for instance "some.package.name.SomeClass", including the classes in nested .jar files
+func (ima *ImportMatcher) readJAR(filePath string, found chan ClassInfo) error {
 	readCloser, err := zip.OpenReader(filePath)
 	if err != nil {
-		return
+		return err
 	}
 	defer readCloser.Close()
 
-	for _, f := range readCloser.File {
-		fileName := f.Name
-		if strings.HasSuffix(fileName, ".class") || strings.HasSuffix(fileName, ".CLASS") {
-
-			// The class name is derived from the .class path within the jar file
-
-			className := strings.TrimSuffix(strings.TrimSuffix(fileName, ".class"), ".CLASS")
-			className = strings.ReplaceAll(className, "/", ".")
-			className = strings.TrimSuffix(className, "$1")
-			className = strings.TrimSuffix(className, "$1")
-			if pos := strings.Index(className, "$"); pos >= 0 {
-				className = className[:pos]
-			}
-
-			if className == "" {
-				continue
-			}
-
-			// Filter out class names that are only lowercase (and '.')
-			allLower := true
-			for _, r := range className {
-				if !unicode.IsLower(r) && r != '.' {
-					allLower = false
-				}
-			}
-			if allLower {
-				continue
-			}
+	readJAREntries(&readCloser.Reader, filePath, 0, found)
+	return nil
+}
 
-			found <- className
+// readCached sends the classes within the given archive to the found chan.
+// If the archive has not changed since it was last scanned, the classes are read from the cache.
+// If not, the given read function is used for scanning the archive, and the result is cached.
+// Archives that can not be read are not cached, and the errors are collected and returned.
+// If the ImportMatcher has no cache, the archive is always scanned.
+func (ima *ImportMatcher) readCached(filePath string, read func(string, chan ClassInfo) error, found chan ClassInfo) error {
+	fi, err := os.Stat(filePath)
+	if err != nil {
+		return ima.addArchiveError(filePath, err)
+	}
+	if ima.cache == nil {
+		if err := read(filePath, found); err != nil {
+			return ima.addArchiveError(filePath, err)
 		}
+		return nil
 	}
+	if classes, ok := ima.cache.lookup(filePath, fi); ok {
+		for _, info := range classes {
+			found <- info
+		}
+		return nil
+	}
+	scanned := make(chan ClassInfo)
+	var readErr error
+	go func() {
+		readErr = read(filePath, scanned)
+		close(scanned)
+	}()
+	var classes []ClassInfo
+	for info := range scanned {
+		classes = append(classes, info)
+		found <- info
+	}
+	if readErr != nil {
+		return ima.addArchiveError(filePath, readErr)
+	}
+	ima.cache.store(filePath, fi, classes)
+	return nil
+}
+
+// addArchiveError stores and returns an error for an archive that could not be read
+func (ima *ImportMatcher) addArchiveError(filePath string, err error) *ArchiveError {
+	archiveError := &ArchiveError{Path: filePath, Err: err}
+	ima.mut.Lock()
+	ima.archiveErrors = append(ima.archiveErrors, archiveError)
+	ima.mut.Unlock()
+	return archiveError
+}
+
+// ArchiveErrors returns the errors for the archives that could not be read while searching for classes.
+// Archives that are found when searching directories are skipped if they can not be read, but an
+// archive that is given directly as a path makes NewCustom and NewWithOptions return an *ArchiveError.
+func (ima *ImportMatcher) ArchiveErrors() []*ArchiveError {
+	ima.mut.RLock()
+	defer ima.mut.RUnlock()
+	return append([]*ArchiveError{}, ima.archiveErrors...)
 }
 
 // findClassesInJarOrSrc will search the given JAR path for JAR files,
 // and then search each JAR file for for classes.
 // Found classes will be sent to the found chan.
-// Will also search "*/lib/src.zip" files.
-func (ima *ImportMatcher) findClassesInJarOrSrc(JARPath string, found chan string) {
+// Will also search "*/lib/src.zip" files, .jmod files, "*/lib/modules" jimage files, .war files
+// and directories of .class files, like "target/classes" and "build/classes/java/main".
+func (ima *ImportMatcher) findClassesInJarOrSrc(JARPath string, found chan ClassInfo) {
 	var wg sync.WaitGroup
 	filepath.Walk(JARPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
@@ -220,17 +406,40 @@ func (ima *ImportMatcher) findClassesInJarOrSrc(JARPath string, found chan strin
 		fileName := info.Name()
 		filePath := path
 
-		if filepath.Ext(fileName) == ".jar" || filepath.Ext(fileName) == ".JAR" {
+		if info.IsDir() && isClassDir(filePath) {
+			wg.Add(1)
+			go func(dirPath string) {
+				if err := readClassDir(dirPath, found); err != nil {
+					ima.addArchiveError(dirPath, err)
+				}
+				wg.Done()
+			}(filePath)
+			return filepath.SkipDir
+		} else if ext := filepath.Ext(fileName); ext == ".jar" || ext == ".JAR" || ext == ".war" {
+			wg.Add(1)
+			go func(filePath string) {
+				ima.readCached(filePath, ima.readJAR, found)
+				wg.Done()
+			}(filePath)
+			return err
+		} else if filepath.Ext(fileName) == ".jmod" {
 			wg.Add(1)
 			go func(filePath string) {
-				ima.readJAR(filePath, found)
+				ima.readCached(filePath, ima.readJMOD, found)
 				wg.Done()
 			}(filePath)
 			return err
 		} else if filepath.Base(filePath) == "src.zip" && filepath.Base(filepath.Dir(filePath)) == "lib" {
 			wg.Add(1)
 			go func(filePath string) {
-				ima.readSOURCE(filePath, found)
+				ima.readCached(filePath, ima.readSOURCE, found)
+				wg.Done()
+			}(filePath)
+			return err
+		} else if filepath.Base(filePath) == "modules" && filepath.Base(filepath.Dir(filePath)) == "lib" && info.Mode().IsRegular() {
+			wg.Add(1)
+			go func(filePath string) {
+				ima.readCached(filePath, ima.readJImage, found)
 				wg.Done()
 			}(filePath)
 			return err
@@ -241,75 +450,230 @@ func (ima *ImportMatcher) findClassesInJarOrSrc(JARPath string, found chan strin
 	wg.Wait()
 }
 
-func (ima *ImportMatcher) produceClasses(found chan string) {
+// produceClasses sends the classes of all the class sources to the found chan, and closes it.
+// The error for each class source, if any, is stored at the same index in sourceErrors.
+func (ima *ImportMatcher) produceClasses(found chan ClassInfo, sourceErrors []error) {
 	var wg sync.WaitGroup
-	for _, JARPath := range ima.JARPaths {
-		// fmt.Printf("About to search for .jar files in %s...\n", JARPath)
+	for i, source := range ima.Sources {
 		wg.Add(1)
-		go func(path string) {
-			ima.findClassesInJarOrSrc(path, found)
+		go func(i int, source ClassSource) {
+			sourceErrors[i] = ima.findClasses(source, found)
 			wg.Done()
-		}(JARPath)
+		}(i, source)
 	}
 	wg.Wait()
 	close(found)
 }
 
-func (ima *ImportMatcher) consumeClasses(found <-chan string, done chan<- bool) {
-	for classPath := range found {
+func (ima *ImportMatcher) consumeClasses(found <-chan ClassInfo, done chan<- bool) {
+	for info := range found {
+		classPath := info.Path
 
-		// Let className be classPath by default, in case the replacements doesn't go through
-		className := classPath
-		if strings.Contains(classPath, ".") {
-			fields := strings.Split(classPath, ".")
-			lastField := fields[len(fields)-1]
-			className = lastField
+		// Skip the versioned entries of multi-release jars that are for later releases than the target release
+		if ima.release > 0 && info.Release > ima.release {
+			continue
 		}
 
-		// Check if the same or a shorter class name name already exists. Also prioritize class paths that does not start with "sun.".
-		ima.mut.RLock()
-		if existingClassPath, ok := ima.classMap[className]; ok && existingClassPath != "" && ((len(existingClassPath) <= len(classPath)) || (!strings.HasPrefix(existingClassPath, "sun.") && strings.HasPrefix(classPath, "sun."))) {
-			ima.mut.RUnlock()
-			continue
+		ima.mut.Lock()
+
+		// Store where the class was found. Information from a parsed class file is preferred over information
+		// that is only based on the name, like for src.zip entries, so that the result does not depend on
+		// which archive is read first. The module name is kept from whichever entry has it.
+		existingInfo, alreadyFound := ima.classInfo[classPath]
+		storedInfo := existingInfo
+		if !alreadyFound || betterClassInfo(info, existingInfo) {
+			storedInfo = info
+			if storedInfo.Module == "" {
+				storedInfo.Module = existingInfo.Module
+			}
+			if len(storedInfo.Members) == 0 {
+				storedInfo.Members, storedInfo.KotlinFacade = existingInfo.Members, existingInfo.KotlinFacade
+			}
+		} else if storedInfo.Module == "" {
+			storedInfo.Module = info.Module
+		}
+		ima.classInfo[classPath] = storedInfo
+
+		// Store the class path under the class name, if it is not already there.
+		// Classes that are not public can not be imported, but they are kept in classInfo, since classes
+		// in the same package are used instead of public classes with the same name. A class that was
+		// stored before it was known to be package-private is removed again.
+		// Nested classes are also stored under their qualified name, like "Map.Entry".
+		wasListed, isListed := alreadyFound && !existingInfo.PackagePrivate, !storedInfo.PackagePrivate
+		if isListed != wasListed || (isListed && storedInfo.Nested != existingInfo.Nested) {
+			if wasListed {
+				ima.removeFromClassMap(classPath, existingInfo.Nested)
+			}
+			if isListed {
+				ima.addToClassMap(classPath, storedInfo.Nested)
+			}
 		}
-		ima.mut.RUnlock()
 
-		// fmt.Println("classPath", classPath)
+		// Store the static members, if they have not already been found in another archive
+		if len(info.Members) > 0 && len(existingInfo.Members) == 0 {
+			storedInfo.Members, storedInfo.KotlinFacade = info.Members, info.KotlinFacade
+			ima.classInfo[classPath] = storedInfo
+			for _, member := range info.Members {
+				ref := memberRef{classPath: classPath, property: member.Property, topLevel: info.KotlinFacade}
+				ima.memberMap[member.Name] = append(ima.memberMap[member.Name], ref)
+			}
+		}
 
-		// Store the new class name and class path
-		ima.mut.Lock()
-		ima.classMap[className] = classPath
 		ima.mut.Unlock()
 	}
+
+	// Rank the class paths for each class name
+	ima.mut.Lock()
+	for _, classPaths := range ima.classMap {
+		sort.SliceStable(classPaths, func(i, j int) bool {
+			// Classes in the project source tree have priority over library classes
+			if iProject, jProject := ima.classInfo[classPaths[i]].Project, ima.classInfo[classPaths[j]].Project; iProject != jProject {
+				return iProject
+			}
+			return lessClassPath(classPaths[i], classPaths[j])
+		})
+	}
+	for _, refs := range ima.memberMap {
+		sort.SliceStable(refs, func(i, j int) bool {
+			return lessClassPath(refs[i].classPath, refs[j].classPath)
+		})
+	}
+	ima.mut.Unlock()
+
 	done <- true
 }
 
+// betterClassInfo checks if the given information about a class should replace the existing information.
+// Classes in the project source tree are preferred, then information from a parsed class file over information
+// that is only based on the name of an entry, then information that includes the module name, and then the base
+// entries of multi-release jars.
+func betterClassInfo(info, existing ClassInfo) bool {
+	if info.Project != existing.Project {
+		return info.Project
+	}
+	if parsed, existingParsed := info.Kind != UnknownKind, existing.Kind != UnknownKind; parsed != existingParsed {
+		return parsed
+	}
+	return (existing.Module == "" && info.Module != "") || (existing.Release > 0 && info.Release == 0)
+}
+
+// addToClassMap stores the given class path under its class name, and under the given nested name, if any
+func (ima *ImportMatcher) addToClassMap(classPath, nested string) {
+	className := classNameOf(classPath)
+	ima.classMap[className] = append(ima.classMap[className], classPath)
+	if nested != "" {
+		ima.classMap[nested] = append(ima.classMap[nested], classPath)
+	}
+}
+
+// removeFromClassMap removes the given class path from its class name, and from the given nested name, if any
+func (ima *ImportMatcher) removeFromClassMap(classPath, nested string) {
+	for _, name := range []string{classNameOf(classPath), nested} {
+		if name == "" {
+			continue
+		}
+		var classPaths []string
+		for _, otherClassPath := range ima.classMap[name] {
+			if otherClassPath != classPath {
+				classPaths = append(classPaths, otherClassPath)
+			}
+		}
+		if len(classPaths) == 0 {
+			delete(ima.classMap, name)
+		} else {
+			ima.classMap[name] = classPaths
+		}
+	}
+}
+
+// internalPrefixes are the beginnings of class paths that are not meant to be imported directly
+var internalPrefixes = []string{"sun.", "com.sun.", "jdk.internal."}
+
+// isInternal checks if the given class path is in a package that is not meant to be imported directly
+func isInternal(classPath string) bool {
+	for _, prefix := range internalPrefixes {
+		if strings.HasPrefix(classPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// lessClassPath is the ranking of class paths for the same class name.
+// It returns true if class path a should be preferred over class path b.
+// Class paths that are not internal (like "sun.") are preferred, then shorter class paths.
+// Class paths of equal length are sorted alphabetically.
+func lessClassPath(a, b string) bool {
+	if aInternal, bInternal := isInternal(a), isInternal(b); aInternal != bInternal {
+		return bInternal
+	}
+	if len(a) != len(b) {
+		return len(a) < len(b)
+	}
+	return a < b
+}
+
+// classNameOf returns the class name of the given class path,
+// for instance "List" for "java.util.List"
+func classNameOf(classPath string) string {
+	if pos := strings.LastIndex(classPath, "."); pos >= 0 {
+		return classPath[pos+1:]
+	}
+	return classPath
+}
+
+// starPathOf returns the import path with a glob for the given class path,
+// for instance "java.util.*" for "java.util.List"
+func starPathOf(classPath string) string {
+	if pos := strings.LastIndex(classPath, "."); pos >= 0 {
+		return classPath[:pos] + ".*"
+	}
+	return "*"
+}
+
 func (ima *ImportMatcher) String() string {
 	var sb strings.Builder
 
 	ima.mut.RLock()
-	for className, classPath := range ima.classMap {
-		sb.WriteString(className + ": " + classPath + "\n")
+	for className, classPaths := range ima.classMap {
+		for _, classPath := range classPaths {
+			sb.WriteString(className + ": " + classPath + "\n")
+		}
 	}
 	ima.mut.RUnlock()
 
 	return sb.String()
 }
 
+// ClassPaths returns all found class paths for the given class name, best ranked first.
+// For instance, "List" could give both "java.util.List" and "java.awt.List".
+// Returns an empty slice if there are no matches.
+func (ima *ImportMatcher) ClassPaths(className string) []string {
+	ima.mut.RLock()
+	defer ima.mut.RUnlock()
+	return append([]string{}, ima.classMap[className]...)
+}
+
+// matchesStart checks if the given class name starts with the given start of a class name.
+// Qualified names of nested classes, like "Map.Entry", only match if the start also contains a ".".
+func matchesStart(className, startOfClassName string) bool {
+	return strings.HasPrefix(className, startOfClassName) && (!strings.Contains(className, ".") || strings.Contains(startOfClassName, "."))
+}
+
 // StarPath takes the start of the class name and tries to return the shortest
 // found class name, and also the import path like "java.io.*"
 // Returns empty strings if there are no matches.
 func (ima *ImportMatcher) StarPath(startOfClassName string) (string, string) {
 	shortestClassName := ""
 	shortestImportPath := ""
-	for className, classPath := range ima.classMap {
-		if strings.HasPrefix(className, startOfClassName) {
+	for className, classPaths := range ima.classMap {
+		if matchesStart(className, startOfClassName) {
+			importPath := starPathOf(classPaths[0])
 			if shortestClassName == "" || len(className) < len(shortestClassName) {
 				shortestClassName = className
-				shortestImportPath = strings.Replace(classPath, className, "*", 1)
+				shortestImportPath = importPath
 			} else if len(className) == len(shortestClassName) {
-				importPath := strings.Replace(classPath, className, "*", 1)
-				if shortestImportPath == "" || len(importPath) < len(shortestImportPath) {
+				if len(importPath) < len(shortestImportPath) || (len(importPath) == len(shortestImportPath) && className < shortestClassName) {
 					shortestClassName = className
 					shortestImportPath = importPath
 				}
@@ -319,62 +683,44 @@ func (ima *ImportMatcher) StarPath(startOfClassName string) (string, string) {
 	return shortestClassName, shortestImportPath
 }
 
-// StarPathExact takes the exact class name and tries to return the shortest
+// StarPathExact takes the exact class name and tries to return the best ranked
 // import path for the matching class, if found, like "java.io.*".
 // Returns empty string if there are no matches.
 func (ima *ImportMatcher) StarPathExact(exactClassName string) string {
-	shortestClassName := ""
-	shortestImportPath := ""
-	for className, classPath := range ima.classMap {
-		if className == exactClassName {
-			if shortestClassName == "" {
-				shortestClassName = className
-				shortestImportPath = strings.Replace(classPath, className, "*", 1)
-			} else if len(className) == len(shortestClassName) {
-				importPath := strings.Replace(classPath, className, "*", 1)
-				if shortestImportPath == "" || len(importPath) < len(shortestImportPath) {
-					shortestClassName = className
-					shortestImportPath = importPath
-				}
-			}
-		}
+	if classPath := ima.ImportPathExact(exactClassName); classPath != "" {
+		return starPathOf(classPath)
 	}
-	return shortestImportPath
+	return ""
 }
 
-// ImportPathExact takes the exact class name and tries to return the shortest
+// ImportPathExact takes the exact class name and tries to return the best ranked
 // specific import path for the matching class. For example, "File" could result
 // in "java.io.File". The function returns an empty string if there are no matches.
 func (ima *ImportMatcher) ImportPathExact(exactClassName string) string {
-	shortestClassName := ""
-	shortestImportPath := ""
-	for className, classPath := range ima.classMap {
-		if className == exactClassName {
-			if shortestClassName == "" {
-				shortestClassName = className
-				shortestImportPath = classPath
-			} else if len(className) == len(shortestClassName) {
-				importPath := classPath
-				if shortestImportPath == "" || len(importPath) < len(shortestImportPath) {
-					shortestClassName = className
-					shortestImportPath = importPath
-				}
-			}
-		}
+	if classPaths := ima.classMap[exactClassName]; len(classPaths) > 0 {
+		return classPaths[0]
 	}
-	return shortestImportPath
+	return ""
 }
 
 // StarPathAll takes the start of the class name and tries to return all
 // found class names, and also the import paths, like "java.io.*".
-// Returns empty strings if there are no matches.
+// The results are sorted by class name, and then by ranking.
+// Returns empty slices if there are no matches.
 func (ima *ImportMatcher) StarPathAll(startOfClassName string) ([]string, []string) {
+	var matchingClassNames []string
+	for className := range ima.classMap {
+		if matchesStart(className, startOfClassName) {
+			matchingClassNames = append(matchingClassNames, className)
+		}
+	}
+	sort.Strings(matchingClassNames)
 	allClassNames := make([]string, 0)
 	allImportPaths := make([]string, 0)
-	for className, classPath := range ima.classMap {
-		if strings.HasPrefix(className, startOfClassName) {
+	for _, className := range matchingClassNames {
+		for _, classPath := range ima.classMap[className] {
 			allClassNames = append(allClassNames, className)
-			allImportPaths = append(allImportPaths, strings.Replace(classPath, className, "*", 1))
+			allImportPaths = append(allImportPaths, starPathOf(classPath))
 		}
 	}
 	return allClassNames, allImportPaths
@@ -382,15 +728,14 @@ func (ima *ImportMatcher) StarPathAll(startOfClassName string) ([]string, []stri
 
 // StarPathAllExact takes the exact class name and tries to return all
 // matching class names, and also the import paths, like "java.io.*".
-// Returns empty strings if there are no matches.
+// The results are sorted by ranking.
+// Returns empty slices if there are no matches.
 func (ima *ImportMatcher) StarPathAllExact(exactClassName string) ([]string, []string) {
 	allClassNames := make([]string, 0)
 	allImportPaths := make([]string, 0)
-	for className, classPath := range ima.classMap {
-		if className == exactClassName {
-			allClassNames = append(allClassNames, className)
-			allImportPaths = append(allImportPaths, strings.Replace(classPath, className, "*", 1))
-		}
+	for _, classPath := range ima.classMap[exactClassName] {
+		allClassNames = append(allClassNames, exactClassName)
+		allImportPaths = append(allImportPaths, starPathOf(classPath))
 	}
 	return allClassNames, allImportPaths
 }
@@ -1,6 +1,8 @@
 package autoimport
 
 import (
+	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -41,3 +43,85 @@ func TestFileInputStream(t *testing.T) {
 		t.Fatalf("Expected java.io.*, got %s\n", foundImport)
 	}
 }
+
+func TestReadSourceWithModules(t *testing.T) {
+	jdkPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(jdkPath, "lib", "src.zip"), nil,
+		"java.desktop/java/awt/Frame.java",
+		"java.base/java/util/Scanner.java",
+	)
+	ima, err := NewCustom([]string{jdkPath}, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if foundImport := ima.StarPathExact("Frame"); foundImport != "java.awt.*" {
+		t.Fatalf("Expected java.awt.*, got %q\n", foundImport)
+	}
+	if moduleName := ima.Module("java.util.Scanner"); moduleName != "java.base" {
+		t.Fatalf("Expected the java.base module, got %q\n", moduleName)
+	}
+}
+
+func TestClassPaths(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "rt.jar"), nil,
+		"sun/awt/List.class",
+		"java/util/List.class",
+		"java/awt/List.class",
+		"java/util/ArrayList.class",
+	)
+	ima, err := NewCustom([]string{libPath}, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	expected := []string{"java.awt.List", "java.util.List", "sun.awt.List"}
+	if classPaths := ima.ClassPaths("List"); strings.Join(classPaths, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v, got %v\n", expected, classPaths)
+	}
+	foundClasses, foundImports := ima.StarPathAllExact("List")
+	if len(foundClasses) != 3 || foundImports[0] != "java.awt.*" || foundImports[2] != "sun.awt.*" {
+		t.Fatalf("Expected all three List classes, got %v\n", foundImports)
+	}
+	foundClasses, _ = ima.StarPathAll("")
+	if len(foundClasses) != 4 || foundClasses[0] != "ArrayList" {
+		t.Fatalf("Expected four classes, starting with ArrayList, got %v\n", foundClasses)
+	}
+	if classPath := ima.ClassMap()["List"]; classPath != "java.awt.List" {
+		t.Fatalf("Expected java.awt.List to be the best ranked List, got %s\n", classPath)
+	}
+}
+
+func TestParsedClassInfoWins(t *testing.T) {
+	// A package-private class in a .jmod file, and the same class in src.zip, where it is not known to be package-private
+	parsed := ClassInfo{Path: "java.util.ArrayPrefixHelpers", Module: "java.base", Archive: "java.base.jmod", Kind: ClassType, PackagePrivate: true}
+	nameOnly := ClassInfo{Path: "java.util.ArrayPrefixHelpers", Module: "java.base", Archive: "src.zip"}
+	nested := ClassInfo{Path: "java.util.Map.Entry", Module: "java.base", Archive: "java.base.jmod", Kind: InterfaceType, Nested: "Map.Entry"}
+	nestedNameOnly := ClassInfo{Path: "java.util.Map.Entry", Archive: "src.zip"}
+
+	for _, order := range [][]ClassInfo{
+		{parsed, nameOnly, nested, nestedNameOnly},
+		{nameOnly, parsed, nestedNameOnly, nested},
+	} {
+		ima := &ImportMatcher{classMap: make(map[string][]string), classInfo: make(map[string]ClassInfo), memberMap: make(map[string][]memberRef)}
+		found := make(chan ClassInfo)
+		done := make(chan bool)
+		go ima.consumeClasses(found, done)
+		for _, info := range order {
+			found <- info
+		}
+		close(found)
+		<-done
+		if classPaths := ima.ClassPaths("ArrayPrefixHelpers"); len(classPaths) != 0 {
+			t.Errorf("Expected a package-private class to not be importable, got %v\n", classPaths)
+		}
+		if info, _ := ima.Info("java.util.ArrayPrefixHelpers"); info.Archive != "java.base.jmod" || !info.PackagePrivate {
+			t.Errorf("Expected the information from the class file, got %+v\n", info)
+		}
+		if classPaths := ima.ClassPaths("Map.Entry"); len(classPaths) != 1 || classPaths[0] != "java.util.Map.Entry" {
+			t.Errorf("Expected the nested class to be found by its qualified name, got %v\n", classPaths)
+		}
+		if info, _ := ima.Info("java.util.Map.Entry"); info.Kind != InterfaceType || info.Module != "java.base" {
+			t.Errorf("Expected the information from the class file, got %+v\n", info)
+		}
+	}
+}
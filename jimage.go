@@ -0,0 +1,188 @@
+package autoimport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+// The jimage file format is used by the "lib/modules" file in JDK 9 and later.
+// The header is followed by a redirect table, an offsets table, a block of
+// location attributes and a block of strings, and then the resources. The class names
+// are found in the index, and the class data is only read for finding static members.
+
+const (
+	jimageMagic      = 0xCAFEDADA
+	jimageHeaderSize = 7 * 4
+
+	// location attribute kinds
+	jimageAttributeEnd          = 0
+	jimageAttributeModule       = 1
+	jimageAttributeParent       = 2
+	jimageAttributeBase         = 3
+	jimageAttributeExtension    = 4
+	jimageAttributeOffset       = 5
+	jimageAttributeCompressed   = 6
+	jimageAttributeUncompressed = 7
+	jimageAttributeCount        = 8
+)
+
+// jimageIndex is the parsed index of a jimage file
+type jimageIndex struct {
+	byteOrder binary.ByteOrder
+	offsets   []uint32
+	locations []byte
+	strings   []byte
+	dataStart int64 // the position of the resources in the file
+}
+
+// readJImageIndex reads the header and the index from the given jimage file
+func readJImageIndex(r io.ReaderAt) (*jimageIndex, error) {
+	header := make([]byte, jimageHeaderSize)
+	if _, err := r.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+
+	var byteOrder binary.ByteOrder = binary.LittleEndian
+	if binary.LittleEndian.Uint32(header) != jimageMagic {
+		if binary.BigEndian.Uint32(header) != jimageMagic {
+			return nil, errors.New("not a jimage file")
+		}
+		byteOrder = binary.BigEndian
+	}
+
+	// header fields: magic, version, flags, resource count, table length, locations size, strings size
+	tableLength := int64(byteOrder.Uint32(header[16:]))
+	locationsSize := int64(byteOrder.Uint32(header[20:]))
+	stringsSize := int64(byteOrder.Uint32(header[24:]))
+
+	redirectSize := tableLength * 4
+	offsetsSize := tableLength * 4
+
+	index := make([]byte, redirectSize+offsetsSize+locationsSize+stringsSize)
+	if _, err := r.ReadAt(index, jimageHeaderSize); err != nil {
+		return nil, err
+	}
+
+	offsetsData := index[redirectSize : redirectSize+offsetsSize]
+	offsets := make([]uint32, tableLength)
+	for i := range offsets {
+		offsets[i] = byteOrder.Uint32(offsetsData[i*4:])
+	}
+
+	return &jimageIndex{
+		byteOrder: byteOrder,
+		offsets:   offsets,
+		locations: index[redirectSize+offsetsSize : redirectSize+offsetsSize+locationsSize],
+		strings:   index[redirectSize+offsetsSize+locationsSize:],
+		dataStart: jimageHeaderSize + int64(len(index)),
+	}, nil
+}
+
+// str returns the zero terminated string at the given offset in the strings block
+func (jim *jimageIndex) str(offset uint64) string {
+	if offset >= uint64(len(jim.strings)) {
+		return ""
+	}
+	s := jim.strings[offset:]
+	if pos := bytes.IndexByte(s, 0); pos >= 0 {
+		s = s[:pos]
+	}
+	return string(s)
+}
+
+// location decodes the location attributes at the given offset in the locations block.
+// Each attribute starts with a byte where the 5 upper bits is the kind and the 3 lower
+// bits is the length of the value minus one. The value is stored in big endian order.
+func (jim *jimageIndex) location(offset uint32) [jimageAttributeCount]uint64 {
+	var attributes [jimageAttributeCount]uint64
+	pos := int(offset)
+	for pos < len(jim.locations) {
+		b := jim.locations[pos]
+		kind := b >> 3
+		if kind == jimageAttributeEnd || kind >= jimageAttributeCount {
+			break
+		}
+		length := int(b&7) + 1
+		if pos+1+length > len(jim.locations) {
+			break
+		}
+		var value uint64
+		for _, vb := range jim.locations[pos+1 : pos+1+length] {
+			value = value<<8 | uint64(vb)
+		}
+		attributes[kind] = value
+		pos += 1 + length
+	}
+	return attributes
+}
+
+// read returns the contents of the resource with the given attributes.
+// Compressed resources are not read, and only the class path is used for them.
+func (jim *jimageIndex) read(f *os.File, attributes [jimageAttributeCount]uint64) ([]byte, error) {
+	if attributes[jimageAttributeCompressed] != 0 {
+		return nil, errors.New("compressed resource")
+	}
+	data := make([]byte, attributes[jimageAttributeUncompressed])
+	_, err := f.ReadAt(data, jim.dataStart+int64(attributes[jimageAttributeOffset]))
+	return data, err
+}
+
+// deprecatedModules returns the names of the modules in the jimage file that are deprecated,
+// according to their module-info.class resources
+func (jim *jimageIndex) deprecatedModules(f *os.File) map[string]bool {
+	deprecated := make(map[string]bool)
+	for _, offset := range jim.offsets {
+		attributes := jim.location(offset)
+		if jim.str(attributes[jimageAttributeBase]) != "module-info" || jim.str(attributes[jimageAttributeExtension]) != "class" {
+			continue
+		}
+		if data, err := jim.read(f, attributes); err == nil && isDeprecatedModule(data) {
+			deprecated[jim.str(attributes[jimageAttributeModule])] = true
+		}
+	}
+	return deprecated
+}
+
+// readJImage returns a list of classes within the given jimage file (typically "lib/modules"),
+// for instance "some.package.name.SomeClass", together with the module names.
+func (ima *ImportMatcher) readJImage(filePath string, found chan ClassInfo) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	jim, err := readJImageIndex(f)
+	if err != nil {
+		return err
+	}
+
+	deprecatedModules := jim.deprecatedModules(f)
+
+	for _, offset := range jim.offsets {
+		attributes := jim.location(offset)
+		if jim.str(attributes[jimageAttributeExtension]) != "class" {
+			continue
+		}
+		moduleName := jim.str(attributes[jimageAttributeModule])
+		if moduleName == "" {
+			continue
+		}
+		// The full resource name is /module/parent/base.extension
+		fileName := jim.str(attributes[jimageAttributeBase]) + ".class"
+		if parent := jim.str(attributes[jimageAttributeParent]); parent != "" {
+			fileName = strings.TrimSuffix(parent, "/") + "/" + fileName
+		}
+		info, ok := classEntryInfo(fileName, func() ([]byte, error) { return jim.read(f, attributes) })
+		if ok {
+			info.Module, info.Archive = moduleName, filePath
+			info.Deprecated = info.Deprecated || deprecatedModules[moduleName]
+			found <- info
+		}
+	}
+	return nil
+}
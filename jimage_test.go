@@ -0,0 +1,99 @@
+package autoimport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeTestJImage writes a jimage file with the given resource names, like "/java.base/java/util/List.class"
+func writeTestJImage(t *testing.T, path string, resourceNames ...string) {
+	t.Helper()
+
+	var stringsBlock bytes.Buffer
+	stringsBlock.WriteByte(0) // offset 0 is the empty string
+	stringOffsets := map[string]uint64{"": 0}
+	addString := func(s string) uint64 {
+		if offset, ok := stringOffsets[s]; ok {
+			return offset
+		}
+		offset := uint64(stringsBlock.Len())
+		stringsBlock.WriteString(s)
+		stringsBlock.WriteByte(0)
+		stringOffsets[s] = offset
+		return offset
+	}
+
+	var locationsBlock bytes.Buffer
+	addAttribute := func(kind byte, value uint64) {
+		locationsBlock.WriteByte(kind<<3 | 7)
+		var b [8]byte
+		binary.BigEndian.PutUint64(b[:], value)
+		locationsBlock.Write(b[:])
+	}
+
+	var offsets []uint32
+	for _, resourceName := range resourceNames {
+		// split /module/parent/base.extension
+		fields := bytes.SplitN([]byte(resourceName[1:]), []byte("/"), 2)
+		moduleName, rest := string(fields[0]), string(fields[1])
+		parent, base := filepath.Split(rest)
+		extension := filepath.Ext(base)
+		base = base[:len(base)-len(extension)]
+
+		offsets = append(offsets, uint32(locationsBlock.Len()))
+		addAttribute(jimageAttributeModule, addString(moduleName))
+		addAttribute(jimageAttributeParent, addString(filepath.Clean(parent)))
+		addAttribute(jimageAttributeBase, addString(base))
+		addAttribute(jimageAttributeExtension, addString(extension[1:]))
+		locationsBlock.WriteByte(jimageAttributeEnd)
+	}
+
+	var buf bytes.Buffer
+	for _, field := range []uint32{jimageMagic, 1 << 16, 0, uint32(len(offsets)), uint32(len(offsets)), uint32(locationsBlock.Len()), uint32(stringsBlock.Len())} {
+		binary.Write(&buf, binary.LittleEndian, field)
+	}
+	for range offsets {
+		binary.Write(&buf, binary.LittleEndian, int32(0)) // redirect table
+	}
+	for _, offset := range offsets {
+		binary.Write(&buf, binary.LittleEndian, offset)
+	}
+	buf.Write(locationsBlock.Bytes())
+	buf.Write(stringsBlock.Bytes())
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadJImage(t *testing.T) {
+	jdkPath := t.TempDir()
+	writeTestJImage(t, filepath.Join(jdkPath, "lib", "modules"),
+		"/java.base/java/util/ArrayList.class",
+		"/java.base/java/util/ArrayList$Itr.class",
+		"/java.desktop/java/awt/Frame.class",
+		"/java.base/java/util/regex/package.html",
+	)
+	ima, err := NewCustom([]string{jdkPath}, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if foundImport := ima.StarPathExact("Frame"); foundImport != "java.awt.*" {
+		t.Fatalf("Expected java.awt.*, got %q\n", foundImport)
+	}
+	if moduleName := ima.Module("java.awt.Frame"); moduleName != "java.desktop" {
+		t.Fatalf("Expected the java.desktop module, got %q\n", moduleName)
+	}
+	if moduleName := ima.Module("java.util.ArrayList"); moduleName != "java.base" {
+		t.Fatalf("Expected the java.base module, got %q\n", moduleName)
+	}
+	if len(ima.ClassMap()) != 2 {
+		t.Fatalf("Expected two classes, got:\n%s", ima.String())
+	}
+}
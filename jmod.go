@@ -0,0 +1,68 @@
+package autoimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// jmodMagic is the header that is placed in front of the zip data in .jmod files
+var jmodMagic = []byte{'J', 'M', 1, 0}
+
+// readJMOD returns a list of classes within the given .jmod file,
+// for instance "some.package.name.SomeClass". The module name is taken from the file name.
+func (ima *ImportMatcher) readJMOD(filePath string, found chan ClassInfo) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := fi.Size() - int64(len(jmodMagic))
+
+	header := make([]byte, len(jmodMagic))
+	if _, err := io.ReadFull(f, header); err != nil || !bytes.Equal(header, jmodMagic) {
+		return errors.New("not a jmod file")
+	}
+
+	// The rest of the .jmod file is a regular zip archive
+	zipReader, err := zip.NewReader(io.NewSectionReader(f, int64(len(jmodMagic)), size), size)
+	if err != nil {
+		return err
+	}
+
+	moduleName := strings.TrimSuffix(filepath.Base(filePath), ".jmod")
+
+	// All classes in a deprecated module are deprecated
+	var deprecated bool
+	for _, zf := range zipReader.File {
+		if zf.Name == "classes/module-info.class" {
+			data, err := readZipFile(zf)
+			deprecated = err == nil && isDeprecatedModule(data)
+			break
+		}
+	}
+
+	for _, zf := range zipReader.File {
+		// Only the classes/ directory contains classes, the other directories contains
+		// native libraries, configuration files, header files and so on.
+		if !strings.HasPrefix(zf.Name, "classes/") {
+			continue
+		}
+		zf := zf
+		if info, ok := classEntryInfo(strings.TrimPrefix(zf.Name, "classes/"), func() ([]byte, error) { return readZipFile(zf) }); ok {
+			info.Module, info.Archive = moduleName, filePath
+			info.Deprecated = info.Deprecated || deprecated
+			found <- info
+		}
+	}
+	return nil
+}
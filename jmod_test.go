@@ -0,0 +1,77 @@
+package autoimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// writeTestArchive writes a zip archive with the given (empty) entries to the given path.
+// The header bytes, if any, are placed in front of the zip data.
+func writeTestArchive(t *testing.T, path string, header []byte, entries ...string) {
+	t.Helper()
+	writeTestZip(t, path, header, entries, nil)
+}
+
+// writeTestJAR writes a .jar file with the given entries and contents to the given path
+func writeTestJAR(t *testing.T, path string, entries map[string][]byte) {
+	t.Helper()
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	writeTestZip(t, path, nil, names, entries)
+}
+
+// writeTestZip writes a zip archive with the given entries, in order, to the given path.
+// The contents of each entry is looked up in the contents map, and is empty if it is not there.
+func writeTestZip(t *testing.T, path string, header []byte, entries []string, contents map[string][]byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.Write(header)
+	zw := zip.NewWriter(&buf)
+	zw.SetOffset(int64(len(header)))
+	for _, entry := range entries {
+		w, err := zw.Create(entry)
+		if err != nil {
+			t.Fatalf("Could not add %s to %s: %v", entry, path, err)
+		}
+		w.Write(contents[entry])
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("Could not write %s: %v", path, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadJMOD(t *testing.T) {
+	jdkPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(jdkPath, "jmods", "java.sql.jmod"), jmodMagic,
+		"classes/java/sql/Connection.class",
+		"classes/java/sql/Connection$1.class",
+		"lib/libsql.so",
+		"conf/sql.properties",
+	)
+	ima, err := NewCustom([]string{jdkPath}, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if classPath := ima.ImportPathExact("Connection"); classPath != "java.sql.Connection" {
+		t.Fatalf("Expected java.sql.Connection, got %q\n", classPath)
+	}
+	if moduleName := ima.Module("java.sql.Connection"); moduleName != "java.sql" {
+		t.Fatalf("Expected the java.sql module, got %q\n", moduleName)
+	}
+	if len(ima.ClassMap()) != 1 {
+		t.Fatalf("Expected only one class, got:\n%s", ima.String())
+	}
+}
@@ -0,0 +1,212 @@
+package autoimport
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ImportLayouts are the named import layouts.
+// The "google" layout is the Google Java Style, the "intellij" layout is the default layout of IntelliJ IDEA
+// for Java and the "ktlint" layout is the default layout of ktlint and of IntelliJ IDEA for Kotlin.
+var ImportLayouts = map[string]string{
+	"lexicographic": "*",
+	"google":        "static *,|,*",
+	"intellij":      "*,|,javax.**,java.**,|,static *",
+	"ktlint":        "*,java.**,javax.**,kotlin.**,^",
+}
+
+// layoutEntry is a single entry in an import layout
+type layoutEntry struct {
+	blank   bool   // "|", a blank line between groups
+	static  bool   // "static ...", only matches static imports
+	alias   bool   // "^", matches imports with an alias
+	project bool   // "$project", matches imports from the same project
+	prefix  string // the package prefix, like "java.", or "" for "*"
+}
+
+// ImportLayout describes how import statements are grouped and ordered
+type ImportLayout struct {
+	spec      string
+	entries   []layoutEntry
+	hasStatic bool // if there are no static entries, static imports are placed as other imports
+	hasAlias  bool // if there are no alias entries, imports with aliases are placed as other imports
+}
+
+// ParseImportLayout parses an import layout, which can either be the name of one of the ImportLayouts,
+// or a comma separated list of patterns, like the ij_kotlin_imports_layout setting of ktlint and IntelliJ.
+// "*" matches all imports that are not matched by a more specific pattern, "java.**" matches imports from
+// the java package and all packages below it, "static *" matches static imports, "^" matches imports with an
+// alias, "$project" matches imports from the same project (imports that start with the first two components
+// of the package of the source file) and "|" is a blank line.
+// Within each group, the imports are sorted lexicographically by import path.
+func ParseImportLayout(s string) (*ImportLayout, error) {
+	if spec, ok := ImportLayouts[strings.TrimSpace(s)]; ok {
+		s = spec
+	}
+	layout := &ImportLayout{spec: s}
+	hasCatchAll := false
+	for _, field := range strings.Split(s, ",") {
+		pattern := strings.TrimSpace(field)
+		var entry layoutEntry
+		if strings.HasPrefix(pattern, "static ") {
+			entry.static = true
+			layout.hasStatic = true
+			pattern = strings.TrimSpace(strings.TrimPrefix(pattern, "static "))
+		}
+		switch {
+		case pattern == "|" && !entry.static:
+			entry.blank = true
+		case pattern == "^" && !entry.static:
+			entry.alias = true
+			layout.hasAlias = true
+		case pattern == "$project":
+			entry.project = true
+		case pattern == "*":
+			hasCatchAll = hasCatchAll || !entry.static
+		case strings.HasSuffix(pattern, ".**") && len(pattern) > 3:
+			entry.prefix = strings.TrimSuffix(pattern, "**")
+		default:
+			return nil, fmt.Errorf("invalid import layout pattern: %q", field)
+		}
+		layout.entries = append(layout.entries, entry)
+	}
+	if !hasCatchAll {
+		return nil, fmt.Errorf("the import layout %q has no \"*\" pattern", s)
+	}
+	return layout, nil
+}
+
+// String returns the import layout as a comma separated list of patterns
+func (layout *ImportLayout) String() string {
+	return layout.spec
+}
+
+// importLineParts returns the import path of the given import line, and if the import is static or has an alias
+func importLineParts(line string) (string, bool, bool) {
+	line = strings.TrimSpace(line)
+	if i := strings.Index(line, "//"); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(strings.NewReplacer(";", " ").Replace(line))
+	if len(fields) > 0 && fields[0] == "import" {
+		fields = fields[1:]
+	}
+	static := len(fields) > 0 && fields[0] == "static"
+	if static {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return "", static, false
+	}
+	alias := len(fields) > 2 && fields[1] == "as"
+	return fields[0], static, alias
+}
+
+// projectPrefix returns the package prefix for the "$project" pattern, given the package of a source file
+func projectPrefix(packageName string) string {
+	if packageName == "" {
+		return ""
+	}
+	fields := strings.Split(packageName, ".")
+	if len(fields) > 2 {
+		fields = fields[:2]
+	}
+	return strings.Join(fields, ".") + "."
+}
+
+// group returns the index of the layout entry that the given import line belongs to
+func (layout *ImportLayout) group(line, packageName string) int {
+	path, static, alias := importLineParts(line)
+	if !layout.hasStatic {
+		static = false
+	}
+	if !layout.hasAlias {
+		alias = false
+	}
+	best, bestLength := -1, -1
+	for i, entry := range layout.entries {
+		if entry.blank || entry.static != static {
+			continue
+		}
+		if alias || entry.alias {
+			if alias && entry.alias {
+				return i
+			}
+			continue
+		}
+		prefix := entry.prefix
+		if entry.project {
+			if prefix = projectPrefix(packageName); prefix == "" {
+				continue
+			}
+		}
+		if strings.HasPrefix(path, prefix) && len(prefix) > bestLength {
+			best, bestLength = i, len(prefix)
+		}
+	}
+	if best < 0 {
+		// Static imports or imports with an alias that are not matched are placed with the other imports
+		for i, entry := range layout.entries {
+			if !entry.blank && !entry.static && !entry.alias && !entry.project && entry.prefix == "" {
+				return i
+			}
+		}
+	}
+	return best
+}
+
+// Order groups and sorts the given import lines according to the layout.
+// The groups are separated by empty strings, where the layout has blank lines.
+// packageName is the package of the source file, for the "$project" pattern.
+func (layout *ImportLayout) Order(lines []string, packageName string) []string {
+	groups := make([][]string, len(layout.entries))
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		i := layout.group(line, packageName)
+		groups[i] = append(groups[i], line)
+	}
+	var ordered []string
+	blankLine := false
+	for i, entry := range layout.entries {
+		if entry.blank {
+			blankLine = len(ordered) > 0
+			continue
+		}
+		if len(groups[i]) == 0 {
+			continue
+		}
+		sort.SliceStable(groups[i], func(a, b int) bool {
+			pathA, _, _ := importLineParts(groups[i][a])
+			pathB, _, _ := importLineParts(groups[i][b])
+			if pathA != pathB {
+				return pathA < pathB
+			}
+			return groups[i][a] < groups[i][b]
+		})
+		if blankLine {
+			ordered = append(ordered, "")
+			blankLine = false
+		}
+		ordered = append(ordered, groups[i]...)
+	}
+	return ordered
+}
+
+// orderImportLines orders the given import lines with the configured import layout,
+// or just sorts them if no layout is configured
+func (ima *ImportMatcher) orderImportLines(lines []string, packageName string) []string {
+	if ima.Layout == nil {
+		sorted := make([]string, 0, len(lines))
+		for _, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				sorted = append(sorted, line)
+			}
+		}
+		sort.Strings(sorted)
+		return sorted
+	}
+	return ima.Layout.Order(lines, packageName)
+}
@@ -0,0 +1,181 @@
+package autoimport
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseImportLayout(t *testing.T) {
+	for name := range ImportLayouts {
+		if _, err := ParseImportLayout(name); err != nil {
+			t.Errorf("Could not parse the %s layout: %v\n", name, err)
+		}
+	}
+	for _, invalid := range []string{"java.**", "*,java.*", "static *", "*,static |"} {
+		if _, err := ParseImportLayout(invalid); err == nil {
+			t.Errorf("Expected an error for the import layout %q\n", invalid)
+		}
+	}
+	layout, err := ParseImportLayout("google")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := layout.String(); s != "static *,|,*" {
+		t.Errorf("Unexpected layout: %q\n", s)
+	}
+}
+
+func TestImportLayoutOrder(t *testing.T) {
+	lines := []string{
+		"import org.junit.Test;",
+		"import static org.junit.Assert.assertEquals;",
+		"import java.util.List;",
+		"import com.example.demo.util.Helper;",
+		"import javax.inject.Inject;",
+		"import java.io.File;",
+	}
+	tests := []struct {
+		layout   string
+		expected []string
+	}{
+		{"lexicographic", []string{
+			"import com.example.demo.util.Helper;",
+			"import java.io.File;",
+			"import java.util.List;",
+			"import javax.inject.Inject;",
+			"import org.junit.Assert.assertEquals;",
+			"import org.junit.Test;",
+		}},
+		{"google", []string{
+			"import static org.junit.Assert.assertEquals;",
+			"",
+			"import com.example.demo.util.Helper;",
+			"import java.io.File;",
+			"import java.util.List;",
+			"import javax.inject.Inject;",
+			"import org.junit.Test;",
+		}},
+		{"intellij", []string{
+			"import com.example.demo.util.Helper;",
+			"import org.junit.Test;",
+			"",
+			"import javax.inject.Inject;",
+			"import java.io.File;",
+			"import java.util.List;",
+			"",
+			"import static org.junit.Assert.assertEquals;",
+		}},
+		{"java.**,javax.**,|,*,|,$project,|,static *", []string{
+			"import java.io.File;",
+			"import java.util.List;",
+			"import javax.inject.Inject;",
+			"",
+			"import org.junit.Test;",
+			"",
+			"import com.example.demo.util.Helper;",
+			"",
+			"import static org.junit.Assert.assertEquals;",
+		}},
+	}
+	for _, test := range tests {
+		layout, err := ParseImportLayout(test.layout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		input := append([]string{}, lines...)
+		if test.layout == "lexicographic" {
+			// Without a "static" pattern, static imports are sorted by import path with the other imports
+			input[1] = "import org.junit.Assert.assertEquals;"
+		}
+		ordered := layout.Order(input, "com.example.demo")
+		if strings.Join(ordered, "\n") != strings.Join(test.expected, "\n") {
+			t.Errorf("Layout %q: expected\n%s\ngot\n%s\n", test.layout, strings.Join(test.expected, "\n"), strings.Join(ordered, "\n"))
+		}
+	}
+}
+
+func TestImportLayoutKotlin(t *testing.T) {
+	layout, err := ParseImportLayout("ktlint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ordered := layout.Order([]string{
+		"import kotlin.math.abs",
+		"import java.io.File as JFile",
+		"import org.example.Widget",
+		"import java.util.List",
+	}, "")
+	expected := []string{
+		"import org.example.Widget",
+		"import java.util.List",
+		"import kotlin.math.abs",
+		"import java.io.File as JFile",
+	}
+	if strings.Join(ordered, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Expected\n%s\ngot\n%s\n", strings.Join(expected, "\n"), strings.Join(ordered, "\n"))
+	}
+}
+
+func TestFixImportsLayout(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "rt.jar"), nil,
+		"java/util/ArrayList.class",
+		"javax/swing/JFrame.class",
+		"org/example/Widget.class",
+	)
+	ima, err := NewWithOptions(Options{
+		Language:    Java,
+		ImportStyle: ExplicitImports,
+		Layout:      "*,|,javax.**,java.**",
+		JARPaths:    []string{libPath},
+	})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	source := `package main;
+
+class Main {
+    ArrayList<Widget> a;
+    JFrame frame;
+}
+`
+	expected := `package main;
+
+import org.example.Widget;
+
+import javax.swing.JFrame;
+import java.util.ArrayList;
+
+class Main {
+    ArrayList<Widget> a;
+    JFrame frame;
+}
+`
+	fixed, err := ima.FixImports([]byte(source), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(fixed) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n", expected, fixed)
+	}
+
+	// The imports are in order, according to the layout
+	problems, err := ima.CheckImports(fixed)
+	if err != nil || len(problems) != 0 {
+		t.Fatalf("Expected no problems, got %v (%v)\n", problems, err)
+	}
+	// Fixing the imports again does not change anything
+	if again, err := ima.FixImports(fixed, false); err != nil || string(again) != expected {
+		t.Fatalf("Expected\n%s\ngot\n%s\n(%v)\n", expected, again, err)
+	}
+
+	unordered := strings.Replace(expected, "import javax.swing.JFrame;\nimport java.util.ArrayList;", "import java.util.ArrayList;\nimport javax.swing.JFrame;", 1)
+	problems, err = ima.CheckImports([]byte(unordered))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 1 || problems[0].Kind != UnorderedImports || problems[0].Import != "javax.swing.JFrame" {
+		t.Fatalf("Expected the javax.swing.JFrame import to be out of order, got %v\n", problems)
+	}
+}
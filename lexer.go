@@ -0,0 +1,319 @@
+package autoimport
+
+import (
+	"errors"
+	"unicode"
+	"unicode/utf8"
+)
+
+// tokenKind is the kind of a token in Java or Kotlin source code
+type tokenKind int
+
+const (
+	tokenIdentifier tokenKind = iota // names and keywords, like "List", "val" or `backticked name`
+	tokenNumber                      // numeric literals, like 42, 0x1F, 1_000L or 1.5e10f
+	tokenString                      // string literals and text blocks, without any template expressions
+	tokenChar                        // character literals, like 'a' or '\n'
+	tokenOperator                    // operators and punctuation, like "::", "->", "." or "{"
+)
+
+// token is a token in Java or Kotlin source code. Comments and whitespace are not tokens.
+type token struct {
+	kind   tokenKind
+	text   string
+	line   int // the line number, starting at 1
+	offset int // the position of the first byte of the token in the source code
+}
+
+// lexer splits Java or Kotlin source code into tokens
+type lexer struct {
+	data   []byte
+	pos    int
+	line   int
+	kotlin bool // Kotlin has string templates and nested block comments, Java does not
+	tokens []token
+}
+
+// multiCharOperators are the operators that consists of more than one character, longest first
+var multiCharOperators = []string{
+	">>>=", "<<=", ">>=", ">>>", "===", "!==", "...", "?.", "?:", "::", "->", "..", "==", "!=", "<=", ">=",
+	"&&", "||", "++", "--", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<", "!!",
+}
+
+// tokenize splits the given Java or Kotlin source code into tokens.
+// An error is returned if a comment, string or character literal is not terminated.
+func tokenize(data []byte, kotlin bool) ([]token, error) {
+	lex := &lexer{data: data, line: 1, kotlin: kotlin}
+	if err := lex.run(0); err != nil {
+		return lex.tokens, err
+	}
+	return lex.tokens, nil
+}
+
+// peek returns the byte at the given offset from the current position, or 0
+func (lex *lexer) peek(offset int) byte {
+	if lex.pos+offset < len(lex.data) {
+		return lex.data[lex.pos+offset]
+	}
+	return 0
+}
+
+// hasPrefix checks if the remaining data starts with the given string
+func (lex *lexer) hasPrefix(s string) bool {
+	return len(lex.data)-lex.pos >= len(s) && string(lex.data[lex.pos:lex.pos+len(s)]) == s
+}
+
+// advance moves the position forward by n bytes, counting newlines
+func (lex *lexer) advance(n int) {
+	for i := 0; i < n && lex.pos < len(lex.data); i++ {
+		if lex.data[lex.pos] == '\n' {
+			lex.line++
+		}
+		lex.pos++
+	}
+}
+
+// emit adds a token that starts at the given position and ends at the current position
+func (lex *lexer) emit(kind tokenKind, start, line int) {
+	lex.tokens = append(lex.tokens, token{kind: kind, text: string(lex.data[start:lex.pos]), line: line, offset: start})
+}
+
+// isIdentifierStart checks if the given rune can start an identifier
+func isIdentifierStart(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r)
+}
+
+// isIdentifierPart checks if the given rune can be part of an identifier
+func isIdentifierPart(r rune) bool {
+	return isIdentifierStart(r) || unicode.IsDigit(r)
+}
+
+// run tokenizes the data until the end, or until a closing brace that ends a string template
+// expression is found (when braceDepth is larger than 0)
+func (lex *lexer) run(braceDepth int) error {
+	for lex.pos < len(lex.data) {
+		start, line := lex.pos, lex.line
+		c := lex.data[lex.pos]
+		r, size := utf8.DecodeRune(lex.data[lex.pos:])
+		switch {
+		case unicode.IsSpace(r):
+			lex.advance(size)
+		case lex.hasPrefix("//"):
+			for lex.pos < len(lex.data) && lex.data[lex.pos] != '\n' {
+				lex.pos++
+			}
+		case lex.hasPrefix("/*"):
+			if err := lex.blockComment(); err != nil {
+				return err
+			}
+		case lex.hasPrefix(`"""`):
+			if err := lex.textBlock(); err != nil {
+				return err
+			}
+		case c == '"':
+			if err := lex.stringLiteral(); err != nil {
+				return err
+			}
+		case c == '\'':
+			if err := lex.charLiteral(); err != nil {
+				return err
+			}
+		case c == '`' && lex.kotlin:
+			lex.advance(1)
+			for lex.pos < len(lex.data) && lex.data[lex.pos] != '`' && lex.data[lex.pos] != '\n' {
+				lex.advance(1)
+			}
+			if lex.peek(0) != '`' {
+				return &ParseError{Line: line, Err: errors.New("unterminated backticked identifier")}
+			}
+			lex.advance(1)
+			lex.tokens = append(lex.tokens, token{kind: tokenIdentifier, text: string(lex.data[start+1 : lex.pos-1]), line: line, offset: start})
+		case isIdentifierStart(r):
+			for lex.pos < len(lex.data) {
+				r, size := utf8.DecodeRune(lex.data[lex.pos:])
+				if !isIdentifierPart(r) {
+					break
+				}
+				lex.pos += size
+			}
+			lex.emit(tokenIdentifier, start, line)
+		case unicode.IsDigit(r) || (c == '.' && unicode.IsDigit(rune(lex.peek(1)))):
+			lex.number()
+			lex.emit(tokenNumber, start, line)
+		default:
+			if braceDepth > 0 {
+				if c == '{' {
+					braceDepth++
+				} else if c == '}' {
+					braceDepth--
+					if braceDepth == 0 {
+						// the end of a ${...} string template expression
+						lex.advance(1)
+						return nil
+					}
+				}
+			}
+			n := size
+			for _, op := range multiCharOperators {
+				if lex.hasPrefix(op) {
+					n = len(op)
+					break
+				}
+			}
+			lex.advance(n)
+			lex.emit(tokenOperator, start, line)
+		}
+	}
+	if braceDepth > 0 {
+		return &ParseError{Line: lex.line, Err: errors.New("unterminated string template")}
+	}
+	return nil
+}
+
+// number skips past a numeric literal, like 42, 0x1F, 0b1010, 1_000L, 1.5e-10f or 2.0
+func (lex *lexer) number() {
+	if lex.peek(0) == '0' && (lex.peek(1) == 'x' || lex.peek(1) == 'X' || lex.peek(1) == 'b' || lex.peek(1) == 'B') {
+		lex.pos += 2
+	}
+	for lex.pos < len(lex.data) {
+		c := lex.data[lex.pos]
+		switch {
+		case c == '.' && unicode.IsDigit(rune(lex.peek(1))):
+			lex.pos++
+		case (c == 'e' || c == 'E' || c == 'p' || c == 'P') && (lex.peek(1) == '-' || lex.peek(1) == '+'):
+			lex.pos += 2
+		case c == '_' || unicode.IsDigit(rune(c)) || unicode.IsLetter(rune(c)):
+			// digits, hex digits, underscores and suffixes like L, f, u or UL
+			lex.pos++
+		default:
+			return
+		}
+	}
+}
+
+// blockComment skips past a /* */ comment. In Kotlin, block comments can be nested.
+func (lex *lexer) blockComment() error {
+	line := lex.line
+	depth := 0
+	for lex.pos < len(lex.data) {
+		switch {
+		case lex.hasPrefix("/*") && (depth == 0 || lex.kotlin):
+			depth++
+			lex.advance(2)
+		case lex.hasPrefix("*/"):
+			depth--
+			lex.advance(2)
+			if depth == 0 {
+				return nil
+			}
+		default:
+			lex.advance(1)
+		}
+	}
+	return &ParseError{Line: line, Err: errors.New("unterminated comment")}
+}
+
+// template handles a Kotlin string template, like $name or ${expression}, at the current position.
+// Returns true if a template was found.
+func (lex *lexer) template() (bool, error) {
+	if !lex.kotlin || lex.peek(0) != '$' {
+		return false, nil
+	}
+	if lex.peek(1) == '{' {
+		lex.advance(2)
+		return true, lex.run(1)
+	}
+	if r, _ := utf8.DecodeRune(lex.data[lex.pos+1:]); lex.pos+1 < len(lex.data) && isIdentifierStart(r) && r != '$' {
+		lex.advance(1)
+		start, line := lex.pos, lex.line
+		for lex.pos < len(lex.data) {
+			r, size := utf8.DecodeRune(lex.data[lex.pos:])
+			if !isIdentifierPart(r) || r == '$' {
+				break
+			}
+			lex.pos += size
+		}
+		lex.emit(tokenIdentifier, start, line)
+		return true, nil
+	}
+	return false, nil
+}
+
+// stringLiteral skips past a "string literal", emitting tokens for any Kotlin string templates
+func (lex *lexer) stringLiteral() error {
+	line := lex.line
+	lex.advance(1)
+	for lex.pos < len(lex.data) {
+		c := lex.data[lex.pos]
+		switch {
+		case c == '\\':
+			lex.advance(2)
+		case c == '"':
+			lex.advance(1)
+			lex.tokens = append(lex.tokens, token{kind: tokenString, line: line})
+			return nil
+		case c == '\n':
+			return &ParseError{Line: line, Err: errors.New("unterminated string literal")}
+		default:
+			found, err := lex.template()
+			if err != nil {
+				return err
+			}
+			if !found {
+				lex.advance(1)
+			}
+		}
+	}
+	return &ParseError{Line: line, Err: errors.New("unterminated string literal")}
+}
+
+// textBlock skips past a Java text block or a Kotlin raw string, like """text""".
+// Kotlin raw strings can contain string templates, but not escape sequences.
+func (lex *lexer) textBlock() error {
+	line := lex.line
+	lex.advance(3)
+	for lex.pos < len(lex.data) {
+		switch {
+		case lex.data[lex.pos] == '\\' && !lex.kotlin:
+			lex.advance(2)
+		case lex.hasPrefix(`"""`):
+			// a raw string may end with more than three quotes, where the extra ones are part of the string
+			for lex.hasPrefix(`""""`) {
+				lex.advance(1)
+			}
+			lex.advance(3)
+			lex.tokens = append(lex.tokens, token{kind: tokenString, line: line})
+			return nil
+		default:
+			found, err := lex.template()
+			if err != nil {
+				return err
+			}
+			if !found {
+				lex.advance(1)
+			}
+		}
+	}
+	return &ParseError{Line: line, Err: errors.New("unterminated text block")}
+}
+
+// charLiteral skips past a character literal, like 'a', '\n' or 'A'
+func (lex *lexer) charLiteral() error {
+	line := lex.line
+	lex.advance(1)
+	for lex.pos < len(lex.data) {
+		switch lex.data[lex.pos] {
+		case '\\':
+			lex.advance(2)
+		case '\'':
+			lex.advance(1)
+			lex.tokens = append(lex.tokens, token{kind: tokenChar, line: line})
+			return nil
+		case '\n':
+			return &ParseError{Line: line, Err: errors.New("unterminated character literal")}
+		default:
+			lex.advance(1)
+		}
+	}
+	return &ParseError{Line: line, Err: errors.New("unterminated character literal")}
+}
@@ -0,0 +1,204 @@
+package autoimport
+
+import (
+	"strings"
+	"testing"
+)
+
+// typeNamesIn returns the type names that are found in the given source code, comma separated
+func typeNamesIn(t *testing.T, sourceCode string, kotlin bool) string {
+	t.Helper()
+	src, err := parseSource([]byte(sourceCode), kotlin)
+	if err != nil {
+		t.Fatalf("Could not parse %q: %v", sourceCode, err)
+	}
+	var words []string
+	for _, typeName := range src.typeNames {
+		words = append(words, typeName.text)
+	}
+	return strings.Join(words, ",")
+}
+
+func TestTypeNames(t *testing.T) {
+	tests := []struct {
+		name       string
+		sourceCode string
+		kotlin     bool
+		expected   string
+	}{
+		{"string literal", `String s = "Scanner File";`, false, "String"},
+		{"string literal with escaped quote", `String s = "Say \"Hello\" to Scanner"; Foo f;`, false, "String,Foo"},
+		{"char literal", `char c = 'X'; char q = '\''; Bar b;`, false, "Bar"},
+		{"text block", "String s = \"\"\"\n    Scanner \"quoted\" File\n    \"\"\"; Baz b;", false, "String,Baz"},
+		{"javadoc", "/**\n * Uses a Scanner and a File.\n */\nclass Main {}", false, ""},
+		{"kdoc", "/**\n * Returns a [Scanner].\n */\nfun scan(): Foo = TODO()", true, "Foo,TODO"},
+		{"trailing comment", `Foo foo = null; /* Scanner */ Bar bar; // File`, false, "Foo,Bar"},
+		{"nested comment in Kotlin", "/* outer /* Inner */ Scanner */ val x: Foo", true, "Foo"},
+		{"generics", `Map<String,List<Foo>> m;`, false, "Map,String,List,Foo"},
+		{"cast", `Object o = (Foo)x;`, false, "Object,Foo"},
+		{"class reference", `val c = Foo::class`, true, "Foo"},
+		{"type annotation without space", `val foo:Bar = bar()`, true, "Bar"},
+		{"explicit type arguments", `val a = arrayOf<Baz>()`, true, "Baz"},
+		{"annotation", `@EntityScan(basePackages = ["com.example"]) class Main`, true, "EntityScan"},
+		{"qualified names", `java.util.List<Map.Entry<String, Foo>> x;`, false, "Map,String,Foo"},
+		{"string template", `println("${Foo.bar()} and $name, but not Scanner")`, true, "Foo"},
+		{"raw string", "val s = \"\"\"Scanner ${Foo()} \\\"\"\"", true, "Foo"},
+		{"package and imports", "package com.Example;\nimport java.util.List;\nimport static org.junit.Assert.assertEquals;\nFoo foo;", false, "Foo"},
+		{"backticked identifier", "fun `test Scanner`() { Foo() }", true, "Foo"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := typeNamesIn(t, test.sourceCode, test.kotlin); got != test.expected {
+				t.Errorf("Expected %q, got %q", test.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseSource(t *testing.T) {
+	const sourceCode = `package com.example.demo
+
+import java.util.*
+import org.junit.Assert.assertEquals as eq
+
+typealias Names = List<String>
+
+class Main {
+    companion object {
+        class Nested
+    }
+}
+`
+	src, err := parseSource([]byte(sourceCode), true)
+	if err != nil {
+		t.Fatalf("Could not parse the source code: %v", err)
+	}
+	if src.packageName != "com.example.demo" {
+		t.Errorf("Expected the com.example.demo package, got %q", src.packageName)
+	}
+	if len(src.imports) != 2 || src.imports[0].path != "java.util.*" || src.imports[1].path != "org.junit.Assert.assertEquals" || src.imports[1].alias != "eq" || src.imports[1].line != 4 {
+		t.Errorf("Unexpected imports: %v", src.imports)
+	}
+	if got := strings.Join(src.declaredTypes, ","); got != "Names,Main,Nested" {
+		t.Errorf("Expected Names,Main,Nested to be declared, got %s", got)
+	}
+	if got := strings.Join(src.topLevelTypes, ","); got != "Names,Main" {
+		t.Errorf("Expected Names,Main to be top-level types, got %s", got)
+	}
+}
+
+func TestMemberNames(t *testing.T) {
+	tokenTexts := func(tokens []token) string {
+		var texts []string
+		for _, tok := range tokens {
+			texts = append(texts, tok.text)
+		}
+		return strings.Join(texts, ",")
+	}
+	javaSource := `class MainTest {
+    void test(int count) {
+        assertEquals(2, add(count, 1));
+        list.stream().collect(toList());
+        if (count > 0) { return; }
+    }
+    List<String> names() { return emptyList(); }
+}`
+	src, _ := parseSource([]byte(javaSource), false)
+	if got := tokenTexts(src.calls); got != "assertEquals,add,toList,emptyList" {
+		t.Errorf("Unexpected calls in Java: %s", got)
+	}
+	for _, name := range []string{"test", "count", "names"} {
+		if !src.declaredNames[name] {
+			t.Errorf("Expected %s to be declared", name)
+		}
+	}
+	if len(src.references) != 0 {
+		t.Errorf("Expected no references in Java, got %s", tokenTexts(src.references))
+	}
+
+	kotlinSource := `fun main() = runBlocking {
+    val total = numbers.sumOf { n -> n * 2 }
+    for (item in items) {
+        launch(start = lazyStart) { println(item) }
+    }
+    delay(defaultTimeout)
+}`
+	src, _ = parseSource([]byte(kotlinSource), true)
+	if got := tokenTexts(src.calls); got != "runBlocking,launch,println,delay" {
+		t.Errorf("Unexpected calls in Kotlin: %s", got)
+	}
+	if got := tokenTexts(src.references); got != "numbers,n,items,lazyStart,item,defaultTimeout" {
+		t.Errorf("Unexpected references in Kotlin: %s", got)
+	}
+	for _, name := range []string{"main", "total", "n", "item"} {
+		if !src.declaredNames[name] {
+			t.Errorf("Expected %s to be declared", name)
+		}
+	}
+}
+
+func TestNestedGenerics(t *testing.T) {
+	javaSource := `class Registry {
+    Map<String, Map<Integer, List<String>>> byName = createMap();
+    Map<String, List<String>> groups(int size) { return emptyMap(); }
+}`
+	tokens, err := tokenize([]byte(javaSource), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var closing []string
+	for _, tok := range tokens {
+		if isClosingAngles(tok.text) {
+			closing = append(closing, tok.text)
+		}
+	}
+	if got := strings.Join(closing, ","); got != ">>>,>,>" {
+		t.Errorf("Expected the closing angle brackets to be tokenized as >>>, > and >, got %s", got)
+	}
+	src, err := parseSource([]byte(javaSource), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"byName", "groups", "size"} {
+		if !src.declaredNames[name] {
+			t.Errorf("Expected %s to be declared", name)
+		}
+	}
+	var calls []string
+	for _, tok := range src.calls {
+		calls = append(calls, tok.text)
+	}
+	if got := strings.Join(calls, ","); got != "createMap,emptyMap" {
+		t.Errorf("Unexpected calls: %s", got)
+	}
+}
+
+func TestQualifiedNames(t *testing.T) {
+	src, err := parseSource([]byte("Map.Entry<String, Dialog.Builder> e = Build.VERSION.SDK_INT > 0 ? a.B : Map . Entry;"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, tok := range src.qualifiedNames {
+		names = append(names, tok.text)
+	}
+	if got := strings.Join(names, ","); got != "Map.Entry,Dialog.Builder,Build.VERSION.SDK_INT" {
+		t.Errorf("Unexpected qualified names: %s", got)
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	for _, sourceCode := range []string{
+		"class Main { /* unterminated",
+		"String s = \"unterminated\n;",
+		"char c = 'x",
+		"String s = \"\"\"\nunterminated",
+	} {
+		if _, err := tokenize([]byte(sourceCode), false); err == nil {
+			t.Errorf("Expected an error when tokenizing %q", sourceCode)
+		}
+	}
+	if _, err := tokenize([]byte(`val s = "${foo(`), true); err == nil {
+		t.Errorf("Expected an error for an unterminated string template")
+	}
+}
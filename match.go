@@ -0,0 +1,95 @@
+package autoimport
+
+import (
+	"sort"
+	"strings"
+)
+
+// Match is a class that is found for a class name, with information about where it was found
+type Match struct {
+	Name     string    `json:"name"`              // the simple class name, like "File"
+	Class    string    `json:"class"`             // the fully qualified class name, like "java.io.File"
+	Package  string    `json:"package"`           // the package, like "java.io"
+	Outer    string    `json:"outer,omitempty"`   // the outer class of a nested class, like "java.util.Map" for "java.util.Map.Entry"
+	Import   string    `json:"import"`            // the import path, like "java.io.*" or "java.io.File"
+	Wildcard bool      `json:"wildcard"`          // true if the import path ends with "*"
+	Archive  string    `json:"archive,omitempty"` // the archive or source file the class was found in
+	Module   string    `json:"module,omitempty"`  // the JDK module, for classes in .jmod files or the jimage file
+	Kind     ClassKind `json:"kind,omitempty"`    // the kind of type, like "interface", if the class file has been read
+	Line     int       `json:"line,omitempty"`    // the line where the class name is first used, for ImportMatches
+
+	// Candidates are all the classes with the same name, best ranked first, if the class name is ambiguous.
+	// Only set by ImportMatches, when none of the existing imports or Preferences decide which class is used.
+	Candidates []string `json:"candidates,omitempty"`
+}
+
+// match creates a Match for the given class path. The import path is a wildcard import, unless DeGlob is set.
+func (ima *ImportMatcher) match(className, classPath string) Match {
+	m := Match{
+		Name:     className,
+		Class:    classPath,
+		Package:  strings.TrimSuffix(starPathOf(classPath), ".*"),
+		Import:   starPathOf(classPath),
+		Wildcard: !ima.DeGlob,
+	}
+	if ima.DeGlob {
+		m.Import = classPath
+	}
+	if info, ok := ima.Info(classPath); ok {
+		m.Archive = info.Archive
+		m.Module = info.Module
+		m.Kind = info.Kind
+		if info.Nested != "" {
+			// Nested classes are imported from the outer class, like "java.util.Map.*", which is not the package
+			m.Outer = m.Package
+			m.Package = strings.TrimSuffix(classPath, "."+info.Nested)
+		}
+	}
+	return m
+}
+
+// Matches takes the start of the class name and returns all matching classes.
+// The results are sorted by class name, and then by ranking, like for StarPathAll.
+func (ima *ImportMatcher) Matches(startOfClassName string) []Match {
+	ima.mut.RLock()
+	var classNames []string
+	for className := range ima.classMap {
+		if matchesStart(className, startOfClassName) {
+			classNames = append(classNames, className)
+		}
+	}
+	ima.mut.RUnlock()
+	sort.Strings(classNames)
+	matches := make([]Match, 0)
+	for _, className := range classNames {
+		matches = append(matches, ima.MatchesExact(className)...)
+	}
+	return matches
+}
+
+// MatchesExact takes the exact class name and returns all matching classes, sorted by ranking
+func (ima *ImportMatcher) MatchesExact(exactClassName string) []Match {
+	matches := make([]Match, 0)
+	for _, classPath := range ima.ClassPaths(exactClassName) {
+		matches = append(matches, ima.match(exactClassName, classPath))
+	}
+	return matches
+}
+
+// ImportMatches returns the best ranked class for each class name in the given Java or Kotlin source code
+// that needs an import, in the order they are first used. These are the classes that ImportBlock generates
+// imports for. The Line field is set to the line where each class name is first used.
+func (ima *ImportMatcher) ImportMatches(data []byte) ([]Match, error) {
+	src, err := parseSource(data, !ima.onlyJava)
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]Match, 0)
+	for _, resolved := range ima.resolveTypeNames(src, ima.NestedImports) {
+		m := ima.match(resolved.name, resolved.classPath)
+		m.Line = resolved.line
+		m.Candidates = resolved.candidates
+		matches = append(matches, m)
+	}
+	return matches, nil
+}
@@ -0,0 +1,39 @@
+package autoimport
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatches(t *testing.T) {
+	libPath := t.TempDir()
+	rtJAR := filepath.Join(libPath, "rt.jar")
+	writeTestArchive(t, rtJAR, nil, "java/util/ArrayList.class", "java/util/HashMap.class", "java/awt/List.class", "java/util/List.class")
+	ima, err := NewCustom([]string{libPath}, true)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+
+	matches := ima.Matches("Li")
+	if len(matches) != 2 || matches[0].Class != "java.awt.List" || matches[1].Class != "java.util.List" {
+		t.Fatalf("Expected java.awt.List and java.util.List, got %+v\n", matches)
+	}
+	expected := Match{Name: "List", Class: "java.awt.List", Package: "java.awt", Import: "java.awt.*", Wildcard: true, Archive: rtJAR}
+	if !reflect.DeepEqual(matches[0], expected) {
+		t.Errorf("Expected %+v, got %+v\n", expected, matches[0])
+	}
+
+	source := "package main;\n\nclass Main {\n    HashMap<String, String> m;\n    ArrayList<String> a;\n    HashMap<String, String> n;\n}\n"
+	ima.DeGlob = true
+	matches, err = ima.ImportMatches([]byte(source))
+	if err != nil {
+		t.Fatalf("Could not find the matches: %v\n", err)
+	}
+	if len(matches) != 2 || matches[0].Name != "HashMap" || matches[0].Line != 4 || matches[1].Name != "ArrayList" || matches[1].Line != 5 {
+		t.Fatalf("Expected HashMap on line 4 and ArrayList on line 5, got %+v\n", matches)
+	}
+	if matches[0].Import != "java.util.HashMap" || matches[0].Wildcard {
+		t.Errorf("Expected an import without a wildcard, got %+v\n", matches[0])
+	}
+}
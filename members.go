@@ -0,0 +1,167 @@
+package autoimport
+
+import (
+	"strings"
+)
+
+// kotlinDefaultPackages are the packages that are imported by default in Kotlin
+var kotlinDefaultPackages = []string{
+	"kotlin", "kotlin.annotation", "kotlin.collections", "kotlin.comparisons", "kotlin.io",
+	"kotlin.jvm", "kotlin.ranges", "kotlin.sequences", "kotlin.text",
+}
+
+// objectMembers are the methods of java.lang.Object and kotlin.Any, which every class inherits,
+// so a call like "toString()" never refers to a static member or a top-level function
+var objectMembers = map[string]bool{
+	"clone": true, "equals": true, "finalize": true, "getClass": true, "hashCode": true,
+	"notify": true, "notifyAll": true, "toString": true, "wait": true,
+}
+
+// resolvedMember is a static member or a Kotlin top-level function or property that is used in the
+// source code, together with the import that provides it
+type resolvedMember struct {
+	name       string
+	importPath string // like "org.junit.Assert.assertEquals" or "kotlinx.coroutines.runBlocking"
+	static     bool   // "import static" in Java
+	imported   bool   // true if the member is already imported by one of the existing imports
+	line       int    // the line where the name is first used
+}
+
+// importLine returns the import statement for the member
+func (member resolvedMember) importLine() string {
+	if member.static {
+		return "import static " + member.importPath + ";"
+	}
+	return "import " + member.importPath + ";"
+}
+
+// memberRefs returns the classes with a static member, or the Kotlin file facades with a
+// top-level function or property, with the given name, best ranked first
+func (ima *ImportMatcher) memberRefs(name string) []memberRef {
+	ima.mut.RLock()
+	defer ima.mut.RUnlock()
+	return append([]memberRef{}, ima.memberMap[name]...)
+}
+
+// memberImport checks if the given member is imported by the given import statement,
+// either explicitly or by a wildcard import of the class (or of the package, for Kotlin top-level members)
+func memberImport(stmt importStatement, name string, ref memberRef, kotlin bool) bool {
+	if !strings.HasSuffix(stmt.path, ".*") {
+		return stmt.path == ref.importPath(name, kotlin)
+	}
+	return strings.TrimSuffix(stmt.path, "*") == strings.TrimSuffix(ref.importPath(name, kotlin), name)
+}
+
+// resolveMembers finds the imports that are needed for the static members (Java) and the top-level functions and
+// properties (Kotlin) that are used without a qualifier in the given source code. Only names that are called are
+// considered for Java, while Kotlin properties are also found when they are just referenced.
+// Names that are declared in the same file are skipped, and so are Kotlin top-level members in the
+// same package or in the packages that are imported by default.
+// If a name is already imported by one of the existing imports, that import is used.
+// Names that may be inherited are only resolved if they are already imported: the methods of java.lang.Object
+// are never imported, and neither are Kotlin names that are only referenced or names that are used in a file
+// with types that extend or implement types that are not declared in the same file.
+func (ima *ImportMatcher) resolveMembers(src *parsedSource) []resolvedMember {
+	kotlin := !ima.onlyJava
+	var resolved []resolvedMember
+	seen := make(map[string]bool)
+	uses := src.calls
+	if kotlin {
+		uses = append(append([]token{}, src.calls...), src.references...)
+	}
+	inherits := src.inheritsUnknownMembers()
+	for _, use := range uses {
+		name := use.text
+		if seen[name] || src.declaredNames[name] {
+			continue
+		}
+		seen[name] = true
+		isCall := false
+		for _, call := range src.calls {
+			if call.text == name {
+				isCall = true
+				break
+			}
+		}
+
+		// Explicitly imported names, like "import static org.junit.Assert.assertEquals", are already available,
+		// even if they are not known
+		var explicit *importStatement
+		for i, stmt := range src.imports {
+			if stmt.alias == name || (stmt.alias == "" && !strings.HasSuffix(stmt.path, ".*") && classNameOf(stmt.path) == name) {
+				explicit = &src.imports[i]
+				break
+			}
+		}
+		if explicit != nil {
+			if explicit.static || (kotlin && explicit.alias == "") {
+				resolved = append(resolved, resolvedMember{name: name, importPath: explicit.path, static: explicit.static, imported: true, line: use.line})
+			}
+			continue
+		}
+		if objectMembers[name] {
+			continue
+		}
+
+		var candidates []memberRef
+		defaultImported := false
+		for _, ref := range ima.memberRefs(name) {
+			if ref.property == isCall {
+				// Functions are called, properties are not
+				continue
+			}
+			if !kotlin && ref.topLevel {
+				continue
+			}
+			if kotlin && ref.topLevel {
+				packageName := strings.TrimSuffix(starPathOf(ref.classPath), ".*")
+				if packageName == src.packageName || hasS(kotlinDefaultPackages, packageName) {
+					defaultImported = true
+					break
+				}
+			}
+			candidates = append(candidates, ref)
+		}
+		if defaultImported || len(candidates) == 0 {
+			continue
+		}
+		onlyImported := inherits || !isCall
+
+		// Prefer a member that is already imported with a wildcard import, then a member in a package that is
+		// already imported from, then Kotlin top-level functions and properties, then the best ranked member
+		best, bestScore := candidates[0], -1
+		imported := false
+		for _, ref := range candidates {
+			score := 0
+			packagePrefix := strings.TrimSuffix(starPathOf(ref.classPath), "*")
+			for _, stmt := range src.imports {
+				if memberImport(stmt, name, ref, kotlin) {
+					score = 3
+					break
+				}
+				if strings.HasPrefix(stmt.path, packagePrefix) && score < 2 {
+					score = 2
+				}
+			}
+			if score == 0 && kotlin && ref.topLevel {
+				score = 1
+			}
+			if score > bestScore {
+				best, bestScore = ref, score
+				imported = score == 3
+			}
+		}
+		if onlyImported && !imported {
+			// The name may be an inherited member or a property of the receiver
+			continue
+		}
+		resolved = append(resolved, resolvedMember{
+			name:       name,
+			importPath: best.importPath(name, kotlin),
+			static:     !kotlin,
+			imported:   imported,
+			line:       use.line,
+		})
+	}
+	return resolved
+}
@@ -0,0 +1,29 @@
+package autoimport
+
+import (
+	"strconv"
+	"strings"
+)
+
+// versionsPrefix is the directory within multi-release jars that contains the classes for each Java release,
+// like "META-INF/versions/11/org/example/Util.class"
+const versionsPrefix = "META-INF/versions/"
+
+// versionedEntry returns the Java release and the entry name within the release of the given versioned entry of a
+// multi-release jar, like 11 and "org/example/Util.class" for "META-INF/versions/11/org/example/Util.class".
+// Returns 0 and the given entry name if it is not a versioned entry, and -1 if the release number is not valid.
+func versionedEntry(entryName string) (int, string) {
+	if !strings.HasPrefix(entryName, versionsPrefix) {
+		return 0, entryName
+	}
+	rest := strings.TrimPrefix(entryName, versionsPrefix)
+	pos := strings.Index(rest, "/")
+	if pos < 0 {
+		return -1, entryName
+	}
+	release, err := strconv.Atoi(rest[:pos])
+	if err != nil || release <= 0 {
+		return -1, entryName
+	}
+	return release, rest[pos+1:]
+}
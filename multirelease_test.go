@@ -0,0 +1,68 @@
+package autoimport
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVersionedEntry(t *testing.T) {
+	for entryName, expected := range map[string]struct {
+		release   int
+		entryName string
+	}{
+		"org/lib/Util.class":                      {0, "org/lib/Util.class"},
+		"META-INF/versions/11/org/lib/Util.class": {11, "org/lib/Util.class"},
+		"META-INF/versions/9/module-info.class":   {9, "module-info.class"},
+		"META-INF/versions/next/org/A.class":      {-1, "META-INF/versions/next/org/A.class"},
+		"META-INF/versions/0/org/A.class":         {-1, "META-INF/versions/0/org/A.class"},
+	} {
+		if release, name := versionedEntry(entryName); release != expected.release || name != expected.entryName {
+			t.Errorf("Expected %d and %s for %s, got %d and %s\n", expected.release, expected.entryName, entryName, release, name)
+		}
+	}
+}
+
+func TestMultiReleaseJAR(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "lib.jar"), nil,
+		"module-info.class",
+		"org/lib/Util.class",
+		"org/lib/package-info.class",
+		"META-INF/versions/9/module-info.class",
+		"META-INF/versions/11/org/lib/Util.class",
+		"META-INF/versions/17/org/lib/Modern.class",
+	)
+	cachePath := filepath.Join(t.TempDir(), "classes.gob")
+
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, CachePath: cachePath})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	for className, classPath := range ima.ClassMap() {
+		if strings.HasPrefix(classPath, "META-INF") || strings.HasSuffix(className, "-info") {
+			t.Errorf("Unexpected class %s (%s)\n", className, classPath)
+		}
+	}
+	if classPaths := ima.ClassPaths("Util"); len(classPaths) != 1 || classPaths[0] != "org.lib.Util" {
+		t.Fatalf("Expected org.lib.Util once, got %v\n", classPaths)
+	}
+	if info, _ := ima.Info("org.lib.Util"); info.Release != 0 {
+		t.Errorf("Expected the base entry to be preferred, got release %d\n", info.Release)
+	}
+	if info, _ := ima.Info("org.lib.Modern"); info.Release != 17 {
+		t.Errorf("Expected release 17 for org.lib.Modern, got %d\n", info.Release)
+	}
+
+	// Classes that only exist for later releases are skipped, also when the classes are read from the cache
+	ima, err = NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, CachePath: cachePath, Release: 11})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if classPath := ima.ImportPathExact("Modern"); classPath != "" {
+		t.Errorf("Expected no class for Java 11, got %s\n", classPath)
+	}
+	if classPath := ima.ImportPathExact("Util"); classPath != "org.lib.Util" {
+		t.Errorf("Expected org.lib.Util, got %q\n", classPath)
+	}
+}
@@ -0,0 +1,60 @@
+package autoimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+)
+
+// maxNestedJARDepth is how deeply .jar files within .jar files are opened
+const maxNestedJARDepth = 3
+
+// classesPrefixes are the directories within Spring Boot jars and .war files
+// that contain the classes of the application itself
+var classesPrefixes = []string{"BOOT-INF/classes/", "WEB-INF/classes/"}
+
+// isNestedJAR checks if the given archive entry is a .jar file within the archive,
+// like "BOOT-INF/lib/spring-core-6.1.2.jar" in a Spring Boot jar or "WEB-INF/lib/guava-33.0.jar" in a .war file
+func isNestedJAR(entryName string) bool {
+	return strings.HasSuffix(entryName, ".jar") || strings.HasSuffix(entryName, ".JAR")
+}
+
+// readJAREntries sends the classes in the given .jar (or .war) archive to the found chan, with the given archive path.
+// The "BOOT-INF/classes/" and "WEB-INF/classes/" prefixes are removed from the class entries, and nested .jar files,
+// like the dependencies in "BOOT-INF/lib/", are opened recursively, up to maxNestedJARDepth levels deep.
+// The classes in a nested .jar file have an archive path like "app.jar!/BOOT-INF/lib/dependency.jar".
+// The versioned entries of multi-release jars are found in their real packages, together with their release.
+// Nested .jar files that can not be read are skipped.
+func readJAREntries(zr *zip.Reader, archivePath string, depth int, found chan ClassInfo) {
+	for _, f := range zr.File {
+		f := f
+		entryName := f.Name
+		if isNestedJAR(entryName) {
+			if depth >= maxNestedJARDepth {
+				continue
+			}
+			data, err := readZipFile(f)
+			if err != nil {
+				continue
+			}
+			nested, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				continue
+			}
+			readJAREntries(nested, archivePath+"!/"+entryName, depth+1, found)
+			continue
+		}
+		for _, prefix := range classesPrefixes {
+			entryName = strings.TrimPrefix(entryName, prefix)
+		}
+		release, entryName := versionedEntry(entryName)
+		if release < 0 {
+			continue
+		}
+		// The class name is derived from the .class path within the jar file
+		if info, ok := classEntryInfo(entryName, func() ([]byte, error) { return readZipFile(f) }); ok {
+			info.Archive, info.Release = archivePath, release
+			found <- info
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package autoimport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNestedJARs(t *testing.T) {
+	libPath := t.TempDir()
+	innerPath := filepath.Join(t.TempDir(), "dependency.jar")
+	writeTestArchive(t, innerPath, nil, "org/dep/Helper.class")
+	inner, err := os.ReadFile(innerPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestJAR(t, filepath.Join(libPath, "app.jar"), map[string][]byte{
+		"BOOT-INF/classes/com/example/Application.class": nil,
+		"BOOT-INF/lib/dependency.jar":                    inner,
+		"BOOT-INF/lib/broken.jar":                        []byte("not a zip file"),
+		"org/springframework/boot/loader/Launcher.class": nil,
+	})
+	writeTestJAR(t, filepath.Join(libPath, "web.war"), map[string][]byte{
+		"WEB-INF/classes/com/example/web/Controller.class": nil,
+	})
+
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	for className, expected := range map[string]string{
+		"Application": "com.example.Application",
+		"Helper":      "org.dep.Helper",
+		"Launcher":    "org.springframework.boot.loader.Launcher",
+		"Controller":  "com.example.web.Controller",
+	} {
+		if classPath := ima.ImportPathExact(className); classPath != expected {
+			t.Errorf("Expected %q for %s, got %q\n", expected, className, classPath)
+		}
+	}
+	if info, _ := ima.Info("org.dep.Helper"); info.Archive != filepath.Join(libPath, "app.jar")+"!/BOOT-INF/lib/dependency.jar" {
+		t.Errorf("Unexpected archive for a class in a nested jar: %s\n", info.Archive)
+	}
+	if len(ima.ArchiveErrors()) != 0 {
+		t.Errorf("Expected nested jars that can not be read to be skipped, got %v\n", ima.ArchiveErrors())
+	}
+}
+
+func TestClassDirectories(t *testing.T) {
+	projectPath := t.TempDir()
+	for _, classPath := range []string{
+		filepath.Join("target", "classes", "com", "example", "Maven.class"),
+		filepath.Join("build", "classes", "kotlin", "main", "com", "example", "Gradle.class"),
+		filepath.Join("other", "classes", "com", "example", "Other.class"),
+	} {
+		path := filepath.Join(projectPath, classPath)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{projectPath}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	for className, expected := range map[string]string{
+		"Maven":  "com.example.Maven",
+		"Gradle": "com.example.Gradle",
+		"Other":  "",
+	} {
+		if classPath := ima.ImportPathExact(className); classPath != expected {
+			t.Errorf("Expected %q for %s, got %q\n", expected, className, classPath)
+		}
+	}
+
+	// A build output directory can also be given directly
+	ima, err = NewWithOptions(Options{Language: Java, JARPaths: []string{filepath.Join(projectPath, "target", "classes")}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if classPath := ima.ImportPathExact("Maven"); classPath != "com.example.Maven" {
+		t.Errorf("Expected com.example.Maven, got %q\n", classPath)
+	}
+}
@@ -0,0 +1,276 @@
+package autoimport
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/xyproto/env/v2"
+)
+
+// Language is the programming language that imports are generated for
+type Language int
+
+const (
+	// Kotlin source code, where both Java and Kotlin classes can be imported
+	Kotlin Language = iota
+	// Java source code, where only Java classes can be imported
+	Java
+)
+
+// ImportStyle is the style of the generated import statements
+type ImportStyle int
+
+const (
+	// WildcardImports generates import statements like "import java.util.*;"
+	WildcardImports ImportStyle = iota
+	// ExplicitImports generates import statements like "import java.util.ArrayList;"
+	ExplicitImports
+)
+
+// CommentStyle decides which comments are added after generated wildcard import statements
+type CommentStyle int
+
+const (
+	// ClassNameComments lists the used classes, like "import java.util.*; // ArrayList, HashMap"
+	ClassNameComments CommentStyle = iota
+	// NoComments generates import statements without comments
+	NoComments
+)
+
+// Options contains the settings for creating an ImportMatcher with NewWithOptions.
+// The zero value is a valid configuration for Kotlin, using the installed JDK and Kotlin.
+type Options struct {
+	Language              Language     // Kotlin (the default) or Java
+	ImportStyle           ImportStyle  // WildcardImports (the default) or ExplicitImports
+	CommentStyle          CommentStyle // ClassNameComments (the default) or NoComments
+	RemoveExistingImports bool         // always start out with removing existing imports
+	RemoveUnusedImports   bool         // remove existing imports that are not used, when keeping existing imports
+	NestedImports         bool         // import nested classes that are used like "Map.Entry", and shorten the names to "Entry"
+	Layout                string       // the name of one of the ImportLayouts, or a list of patterns. If empty, imports are just sorted.
+	Preferences           *Preferences // which classes to import when class names are ambiguous, if any
+	Ranker                Ranker       // ranks the classes with the same name for a source file. If nil, DefaultRanker is used.
+
+	JARPaths     []string      // paths to search for .jar files. If empty, the Java (and Kotlin) installations are searched.
+	Dependencies bool          // also search the newest version of each artifact in the local Maven repository and Gradle cache
+	ProjectFile  string        // if set, also search the dependencies and the source tree of the project of this file
+	Sources      []ClassSource // other places to search for classes, in addition to the JARPaths
+	Release      int           // the target Java release, like 17. Classes that only exist for later releases in multi-release jars are skipped. If 0, all releases are used.
+
+	CachePath    string      // the class index cache file. If empty, DefaultCachePath() is used.
+	RebuildCache bool        // scan all archives again, instead of using the cached classes
+	Logger       *log.Logger // where verbose output, and problems with the dependencies of the project, are written. If nil, verbose output is written to stdout, and problems to stderr.
+}
+
+// settingsOptions converts the optional bool settings of New and NewCustom to Options.
+// Other options, like Dependencies, are only available with NewWithOptions.
+func settingsOptions(settings ...bool) Options {
+	var opts Options
+	if len(settings) > 0 && settings[0] {
+		opts.Language = Java
+	}
+	if len(settings) > 1 {
+		opts.RemoveExistingImports = settings[1]
+	}
+	if len(settings) > 2 && settings[2] {
+		opts.ImportStyle = ExplicitImports
+	}
+	return opts
+}
+
+// NewWithOptions creates a new ImportMatcher, given the options
+func NewWithOptions(opts Options) (*ImportMatcher, error) {
+	ima := &ImportMatcher{
+		onlyJava:              opts.Language == Java,
+		release:               opts.Release,
+		removeExistingImports: opts.RemoveExistingImports,
+		DeGlob:                opts.ImportStyle == ExplicitImports,
+		RemoveUnusedImports:   opts.RemoveUnusedImports,
+		NestedImports:         opts.NestedImports,
+		Preferences:           opts.Preferences,
+		Ranker:                opts.Ranker,
+		commentStyle:          opts.CommentStyle,
+		logger:                opts.Logger,
+	}
+
+	if opts.Layout != "" {
+		layout, err := ParseImportLayout(opts.Layout)
+		if err != nil {
+			return nil, err
+		}
+		ima.Layout = layout
+	}
+
+	JARSearchPaths := opts.JARPaths
+	if len(JARSearchPaths) == 0 {
+		installationPaths, err := installationPaths(ima.onlyJava)
+		if err != nil {
+			return nil, err
+		}
+		JARSearchPaths = installationPaths
+		if !ima.onlyJava {
+			// The classes in the Kotlin installation are left out when the index is used for Java files
+			ima.kotlinPath, _ = resolveJARPath(installationPaths[1])
+		}
+	}
+	if opts.Dependencies {
+		JARSearchPaths = append(JARSearchPaths, FindDependencyJARs()...)
+	}
+
+	var sourcePaths []string
+	if opts.ProjectFile != "" {
+		if buildFile := FindBuildFile(opts.ProjectFile); buildFile != "" {
+			// A build file that can not be read should not stop the other classes from being found
+			if deps, err := ReadDependencies(buildFile); err != nil {
+				ima.warnf("%s: could not read the dependencies: %v", buildFile, err)
+			} else {
+				JARPaths, missing := ResolveClasspath(deps)
+				for _, dep := range missing {
+					ima.warnf("%s: %s was not found in the local Maven repository or Gradle cache", buildFile, dep)
+				}
+				JARSearchPaths = append(JARSearchPaths, JARPaths...)
+				ima.dependencyArchives = make(map[string]bool, len(JARPaths))
+				for _, JARPath := range JARPaths {
+					ima.dependencyArchives[JARPath] = true
+				}
+			}
+		}
+		sourcePaths = ProjectSourcePaths(opts.ProjectFile)
+	}
+
+	cachePath := opts.CachePath
+	if cachePath == "" {
+		cachePath = DefaultCachePath()
+	}
+	ima.cache = loadCache(cachePath, opts.RebuildCache || env.Bool("AUTOIMPORT_REBUILD_CACHE"))
+
+	if err := ima.index(JARSearchPaths, sourcePaths, opts.Sources); err != nil {
+		return nil, err
+	}
+	return ima, nil
+}
+
+// ForLanguage returns an ImportMatcher for the given language that shares the class index
+// of this ImportMatcher, so that Java and Kotlin files can be fixed without indexing everything twice.
+// An ImportMatcher that is created for Kotlin also has the Java classes, but not the other way around.
+// An ImportMatcher for Java that is returned for an ImportMatcher for Kotlin does not have the classes
+// in the Kotlin installation, so that it finds the same classes as an ImportMatcher created for Java.
+// When it has been created, an ImportMatcher is only read from, and it is safe to use it from
+// several goroutines at the same time, as long as the exported fields are not changed.
+func (ima *ImportMatcher) ForLanguage(language Language) *ImportMatcher {
+	ima.mut.RLock()
+	defer ima.mut.RUnlock()
+	view := &ImportMatcher{
+		classMap:              ima.classMap,
+		classInfo:             ima.classInfo,
+		memberMap:             ima.memberMap,
+		JARPaths:              ima.JARPaths,
+		SourcePaths:           ima.SourcePaths,
+		Sources:               ima.Sources,
+		onlyJava:              language == Java,
+		kotlinPath:            ima.kotlinPath,
+		shared:                ima.shared,
+		release:               ima.release,
+		removeExistingImports: ima.removeExistingImports,
+		DeGlob:                ima.DeGlob,
+		RemoveUnusedImports:   ima.RemoveUnusedImports,
+		NestedImports:         ima.NestedImports,
+		Layout:                ima.Layout,
+		Preferences:           ima.Preferences,
+		Ranker:                ima.Ranker,
+		projectImports:        ima.projectImports,
+		dependencyArchives:    ima.dependencyArchives,
+		commentStyle:          ima.commentStyle,
+		logger:                ima.logger,
+		cache:                 ima.cache,
+		archiveErrors:         ima.archiveErrors,
+	}
+	if ima.shared != nil {
+		index := ima.shared
+		if language == Java {
+			index = index.forJava(ima.kotlinPath)
+		}
+		view.classMap, view.classInfo, view.memberMap = index.classMap, index.classInfo, index.memberMap
+	}
+	return view
+}
+
+// sharedIndex is the class index of an ImportMatcher that has searched the Kotlin installation for classes.
+// The index without the classes in the Kotlin installation is created when it is first needed for Java files.
+type sharedIndex struct {
+	classMap  map[string][]string
+	classInfo map[string]ClassInfo
+	memberMap map[string][]memberRef
+	javaOnce  sync.Once
+	java      *sharedIndex
+}
+
+// forJava returns the index without the classes that were found within the given Kotlin installation
+func (index *sharedIndex) forJava(kotlinPath string) *sharedIndex {
+	index.javaOnce.Do(func() {
+		java := &sharedIndex{
+			classMap:  make(map[string][]string, len(index.classMap)),
+			classInfo: make(map[string]ClassInfo, len(index.classInfo)),
+			memberMap: make(map[string][]memberRef, len(index.memberMap)),
+		}
+		for classPath, info := range index.classInfo {
+			if !isWithin(info.Archive, kotlinPath) {
+				java.classInfo[classPath] = info
+			}
+		}
+		for className, classPaths := range index.classMap {
+			var javaClassPaths []string
+			for _, classPath := range classPaths {
+				if _, ok := java.classInfo[classPath]; ok {
+					javaClassPaths = append(javaClassPaths, classPath)
+				}
+			}
+			if len(javaClassPaths) > 0 {
+				java.classMap[className] = javaClassPaths
+			}
+		}
+		for name, refs := range index.memberMap {
+			var javaRefs []memberRef
+			for _, ref := range refs {
+				if _, ok := java.classInfo[ref.classPath]; ok {
+					javaRefs = append(javaRefs, ref)
+				}
+			}
+			if len(javaRefs) > 0 {
+				java.memberMap[name] = javaRefs
+			}
+		}
+		index.java = java
+	})
+	return index.java
+}
+
+// logf writes a verbose message to the configured logger, or to stdout if no logger is configured
+func (ima *ImportMatcher) logf(format string, args ...interface{}) {
+	if ima.logger != nil {
+		ima.logger.Printf(format, args...)
+		return
+	}
+	fmt.Print(logLine(format, args...))
+}
+
+// warnf writes a problem that does not stop the classes from being indexed, like a dependency that
+// could not be found, to the configured logger, or to stderr if no logger is configured
+func (ima *ImportMatcher) warnf(format string, args ...interface{}) {
+	if ima.logger != nil {
+		ima.logger.Printf(format, args...)
+		return
+	}
+	fmt.Fprint(os.Stderr, logLine(format, args...))
+}
+
+// logLine formats a message for logf or warnf, and makes sure that it ends with a newline
+func logLine(format string, args ...interface{}) string {
+	msg := fmt.Sprintf(format, args...)
+	if !strings.HasSuffix(msg, "\n") {
+		msg += "\n"
+	}
+	return msg
+}
@@ -0,0 +1,209 @@
+package autoimport
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSettingsOptions(t *testing.T) {
+	opts := settingsOptions(true, true, true)
+	if opts.Language != Java || !opts.RemoveExistingImports || opts.ImportStyle != ExplicitImports {
+		t.Fatalf("Unexpected options: %+v\n", opts)
+	}
+	if opts := settingsOptions(true, true, true, true); opts.Dependencies {
+		t.Fatalf("Expected Dependencies to only be set with NewWithOptions: %+v\n", opts)
+	}
+	if opts := settingsOptions(); opts.Language != Kotlin || opts.ImportStyle != WildcardImports {
+		t.Fatalf("Unexpected default options: %+v\n", opts)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "rt.jar"), nil, "java/util/ArrayList.class", "java/util/HashMap.class")
+
+	var logBuffer bytes.Buffer
+	ima, err := NewWithOptions(Options{
+		Language:     Java,
+		CommentStyle: NoComments,
+		JARPaths:     []string{libPath},
+		CachePath:    filepath.Join(t.TempDir(), "classes.gob"),
+		Logger:       log.New(&logBuffer, "", 0),
+	})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	importBlock, err := ima.ImportBlock([]byte("class Main { ArrayList<String> a; HashMap<String, String> m; }"), true)
+	if err != nil {
+		t.Fatalf("Could not generate the import block: %v\n", err)
+	}
+	if string(importBlock) != "import java.util.*;" {
+		t.Fatalf("Expected an import without comments, got:\n%s\n", importBlock)
+	}
+	if !strings.Contains(logBuffer.String(), "ArrayList\t->\timport java.util.*;") {
+		t.Fatalf("Expected verbose output to be written to the logger, got:\n%s\n", logBuffer.String())
+	}
+
+	if _, err := NewCustom(nil, true); err == nil {
+		t.Fatalf("Expected an error when there are no paths to search\n")
+	}
+}
+
+func TestProjectDependencyProblems(t *testing.T) {
+	homePath := t.TempDir()
+	setenv(t, "HOME", homePath)
+	setenv(t, "GRADLE_USER_HOME", filepath.Join(homePath, ".gradle"))
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "rt.jar"), nil, "java/util/ArrayList.class")
+	projectPath := t.TempDir()
+	sourcePath := filepath.Join(projectPath, "Main.java")
+
+	for _, test := range []struct {
+		pom, expected string
+	}{
+		{"<project><dependencies>", "could not read the dependencies"},
+		{"<project><dependencies><dependency><groupId>com.example</groupId><artifactId>not-cached</artifactId><version>1.0</version></dependency></dependencies></project>", "com.example:not-cached:1.0 was not found"},
+	} {
+		if err := os.WriteFile(filepath.Join(projectPath, "pom.xml"), []byte(test.pom), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		var logBuffer bytes.Buffer
+		ima, err := NewWithOptions(Options{
+			Language:    Java,
+			JARPaths:    []string{libPath},
+			ProjectFile: sourcePath,
+			CachePath:   filepath.Join(t.TempDir(), "classes.gob"),
+			Logger:      log.New(&logBuffer, "", 0),
+		})
+		if err != nil {
+			t.Fatalf("Expected the other classes to be indexed when the dependencies can not be found, got %v\n", err)
+		}
+		if classPath := ima.ImportPathExact("ArrayList"); classPath != "java.util.ArrayList" {
+			t.Errorf("Expected java.util.ArrayList, got %q\n", classPath)
+		}
+		if !strings.Contains(logBuffer.String(), test.expected) {
+			t.Errorf("Expected %q to be logged, got:\n%s\n", test.expected, logBuffer.String())
+		}
+	}
+
+	// Without a Logger, the problems are written to stderr, so that they are not mixed with fixed source code on stdout
+	stdout, stderr := captureOutput(t, func() {
+		NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, ProjectFile: sourcePath, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	})
+	if stdout != "" || !strings.Contains(stderr, "was not found") {
+		t.Errorf("Expected the problems to only be written to stderr, got %q on stdout and %q on stderr\n", stdout, stderr)
+	}
+}
+
+// captureOutput returns what the given function writes to stdout and stderr
+func captureOutput(t *testing.T, f func()) (string, string) {
+	t.Helper()
+	oldStdout, oldStderr := os.Stdout, os.Stderr
+	defer func() {
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+	}()
+	var outputs [2]bytes.Buffer
+	var done [2]chan bool
+	for i, file := range []**os.File{&os.Stdout, &os.Stderr} {
+		r, w, err := os.Pipe()
+		if err != nil {
+			t.Fatal(err)
+		}
+		*file = w
+		done[i] = make(chan bool)
+		go func(i int) {
+			outputs[i].ReadFrom(r)
+			r.Close()
+			close(done[i])
+		}(i)
+	}
+	f()
+	os.Stdout.Close()
+	os.Stderr.Close()
+	<-done[0]
+	<-done[1]
+	return outputs[0].String(), outputs[1].String()
+}
+
+func TestForLanguage(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "rt.jar"), nil, "java/util/ArrayList.class", "java/util/HashMap.class")
+	ima, err := NewWithOptions(Options{Language: Kotlin, JARPaths: []string{libPath}})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	java := ima.ForLanguage(Java)
+	kotlin := ima.ForLanguage(Kotlin)
+
+	// The shared index can be used by several goroutines at the same time
+	javaSource := []byte("class Main { ArrayList<String> a; HashMap<String, String> m; }")
+	kotlinSource := []byte("fun main() { val names = ArrayList<String>() }")
+	errs := make(chan error, 20)
+	for i := 0; i < 10; i++ {
+		go func() {
+			importBlock, err := java.ImportBlock(javaSource, false)
+			if err == nil && string(importBlock) != "import java.util.*; // ArrayList, HashMap" {
+				err = fmt.Errorf("unexpected Java imports: %q", importBlock)
+			}
+			errs <- err
+		}()
+		go func() {
+			importBlock, err := kotlin.ImportBlock(kotlinSource, false)
+			if err == nil && string(importBlock) != "import java.util.*; // ArrayList" {
+				err = fmt.Errorf("unexpected Kotlin imports: %q", importBlock)
+			}
+			errs <- err
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestForLanguageWithoutKotlin(t *testing.T) {
+	javaPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(javaPath, "lib", "rt.jar"), nil, "java/util/ArrayList.class", "java/util/HashMap.class")
+	kotlinPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(kotlinPath, "lib", "kotlin-stdlib.jar"), nil, "kotlin/Pair.class", "kotlin/collections/ArraysKt.class")
+	binPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(binPath, "kotlinc"), []byte("#!/bin/sh\nKOTLIN_HOME="+kotlinPath+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	setenv(t, "JAVA_HOME", javaPath)
+	setenv(t, "PATH", binPath)
+
+	ima, err := NewWithOptions(Options{Language: Kotlin, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	java := ima.ForLanguage(Java)
+	for _, view := range []*ImportMatcher{java, java.ReloadSources(), java.ForLanguage(Java)} {
+		if classPaths := view.ClassPaths("Pair"); len(classPaths) != 0 {
+			t.Errorf("Expected no Kotlin classes for Java, got %v\n", classPaths)
+		}
+		if className, importPath := view.StarPath("Pa"); className != "" {
+			t.Errorf("Expected no Kotlin classes for Java, got %s from %s\n", className, importPath)
+		}
+		for _, m := range view.Matches("A") {
+			if strings.HasPrefix(m.Class, "kotlin.") {
+				t.Errorf("Expected no Kotlin classes for Java, got %s\n", m.Class)
+			}
+		}
+		if classPaths := view.ClassPaths("ArrayList"); len(classPaths) != 1 || classPaths[0] != "java.util.ArrayList" {
+			t.Errorf("Expected the Java classes to be found, got %v\n", classPaths)
+		}
+	}
+	// The Kotlin classes are still there for Kotlin
+	for _, view := range []*ImportMatcher{ima, java.ForLanguage(Kotlin), java.ReloadSources().ForLanguage(Kotlin)} {
+		if classPaths := view.ClassPaths("Pair"); len(classPaths) != 1 || classPaths[0] != "kotlin.Pair" {
+			t.Errorf("Expected the Kotlin classes to be found for Kotlin, got %v\n", classPaths)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package autoimport
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// importStatement is an import statement that is found in Java or Kotlin source code
+type importStatement struct {
+	path   string // the imported path, like "java.util.List" or "java.util.*"
+	static bool   // "import static" in Java
+	alias  string // "import a.b.C as D" in Kotlin
+	line   int
+}
+
+// parsedSource contains what is found when tokenizing Java or Kotlin source code
+type parsedSource struct {
+	packageName    string            // the package name, if declared
+	imports        []importStatement // the import statements
+	declaredTypes  []string          // the names of all types that are declared in the source code, including type aliases
+	topLevelTypes  []string          // the names of the types that are declared outside of any braces
+	typeNames      []token           // identifiers that may refer to types that need to be imported
+	qualifiedNames []token           // qualified names that start with a type name, like "Map.Entry" or "Color.RED"
+	identifiers    map[string]bool   // all identifiers that are used outside of package and import statements
+	calls          []token           // lowercase names that are called without a qualifier, like "assertEquals(a, b)"
+	references     []token           // lowercase names that are used without a qualifier, without being called (Kotlin only)
+	declaredNames  map[string]bool   // the names of declared methods, functions, variables and parameters
+	supertypes     []string          // the names of the classes and interfaces that the declared types, including anonymous classes, extend or implement
+}
+
+// inheritsUnknownMembers checks if one of the types in the source code extends or implements a type that is not
+// declared in the same file, so that names that are used without a qualifier may be inherited members
+func (src *parsedSource) inheritsUnknownMembers() bool {
+	for _, supertype := range src.supertypes {
+		if !hasS(src.declaredTypes, supertype) {
+			return true
+		}
+	}
+	return false
+}
+
+// keywords are the lowercase keywords, soft keywords and modifiers of Java and Kotlin,
+// which are never the names of static members or top-level functions
+var keywords = map[string]bool{
+	"abstract": true, "actual": true, "annotation": true, "as": true, "assert": true, "boolean": true,
+	"break": true, "by": true, "byte": true, "case": true, "catch": true, "char": true, "class": true,
+	"companion": true, "const": true, "constructor": true, "continue": true, "crossinline": true,
+	"data": true, "default": true, "do": true, "double": true, "dynamic": true, "else": true, "enum": true,
+	"expect": true, "extends": true, "external": true, "false": true, "field": true, "file": true,
+	"final": true, "finally": true, "float": true, "for": true, "fun": true, "get": true, "goto": true,
+	"if": true, "implements": true, "import": true, "in": true, "infix": true, "init": true, "inline": true,
+	"inner": true, "instanceof": true, "int": true, "interface": true, "internal": true, "is": true,
+	"it": true, "lateinit": true, "long": true, "native": true, "new": true, "noinline": true,
+	"null": true, "object": true, "open": true, "operator": true, "out": true, "override": true,
+	"package": true, "permits": true, "private": true, "protected": true, "public": true, "record": true,
+	"reified": true, "return": true, "sealed": true, "set": true, "short": true, "static": true,
+	"strictfp": true, "super": true, "suspend": true, "switch": true, "synchronized": true,
+	"tailrec": true, "this": true, "throw": true, "throws": true, "transient": true, "true": true,
+	"try": true, "typealias": true, "val": true, "value": true, "var": true, "vararg": true, "void": true,
+	"volatile": true, "when": true, "where": true, "while": true, "yield": true,
+}
+
+// javaExpressionKeywords are the Java keywords that can be followed by an expression.
+// Other identifiers that are followed by a name are types, so the name is declared.
+var javaExpressionKeywords = []string{"return", "new", "throw", "case", "else", "yield", "assert", "instanceof", "do"}
+
+// isDeclaredName checks if the identifier at the given position is the name of a declared method,
+// function, variable or parameter, like "foo" in "int foo(" or "val foo ="
+func isDeclaredName(tokens []token, i int, kotlin bool) bool {
+	var prev, next token
+	if i > 0 {
+		prev = tokens[i-1]
+	}
+	if i+1 < len(tokens) {
+		next = tokens[i+1]
+	}
+	if kotlin {
+		// "fun foo(", "val foo", "var foo", a parameter like "foo: Int" or a loop variable like "for (foo in"
+		return prev.text == "fun" || prev.text == "val" || prev.text == "var" || next.text == ":" || (prev.text == "(" && next.text == "in")
+	}
+	// "void foo(", "List<String> foo(", "Map<K, List<V>> foo", "String[] foo" or "int foo"
+	return (prev.kind == tokenIdentifier && !hasS(javaExpressionKeywords, prev.text)) || isClosingAngles(prev.text) || prev.text == "]"
+}
+
+// isClosingAngles checks if the given token only consists of ">" characters, like ">" or ">>>",
+// since the closing angle brackets of nested generic types are tokenized as shift operators
+func isClosingAngles(text string) bool {
+	return text != "" && strings.Trim(text, ">") == ""
+}
+
+// markLambdaParameters marks the parameters of a Kotlin lambda, like "a" and "b" in "{ a, b ->", as declared names,
+// given the position of the "->" operator
+func markLambdaParameters(tokens []token, i int, declaredNames map[string]bool) {
+	for j := i - 1; j >= 0; j-- {
+		switch tok := tokens[j]; {
+		case tok.kind == tokenIdentifier:
+			declaredNames[tok.text] = true
+		case tok.text == "," || tok.text == ":" || tok.text == "(" || tok.text == ")":
+		default:
+			return
+		}
+	}
+}
+
+// qualifiedTypeName returns the qualified name that starts with the type name at the given position, like
+// "Map.Entry" for "Map.Entry<K, V>" or "Build.VERSION.SDK_INT", if the following names also start with an uppercase
+// letter. Only names that are written without spaces are included, so that they can be shortened in the source code.
+func qualifiedTypeName(tokens []token, i int) string {
+	name := tokens[i].text
+	for j := i + 2; j < len(tokens); j += 2 {
+		dot, next := tokens[j-1], tokens[j]
+		r, _ := utf8.DecodeRuneInString(next.text)
+		if dot.text != "." || dot.offset != tokens[j-2].offset+len(tokens[j-2].text) || next.offset != dot.offset+1 ||
+			next.kind != tokenIdentifier || !unicode.IsUpper(r) {
+			break
+		}
+		name += "." + next.text
+	}
+	return name
+}
+
+// typeDeclarationKeywords are the keywords that are followed by the name of a declared type
+var typeDeclarationKeywords = []string{"class", "interface", "enum", "record", "object", "typealias"}
+
+// supertypeNames returns the names of the classes and interfaces that the type that is declared with the keyword at the
+// given position extends or implements, like "ArrayList" for "class Names extends ArrayList<String> {" or "Base" for
+// "class Foo : Base() {". Enums extend Enum. Qualified names, like "java.util.ArrayList", give the type names.
+func supertypeNames(tokens []token, i int, kotlin bool) []string {
+	var names []string
+	if tokens[i].text == "enum" || (i > 0 && tokens[i-1].text == "enum") {
+		names = append(names, "Enum")
+	}
+	collecting := false
+	parens, angles := 0, 0
+	for j := i + 1; j < len(tokens); j++ {
+		tok := tokens[j]
+		switch {
+		case tok.text == "{" || tok.text == ";":
+			return names
+		case tok.text == "(":
+			parens++
+		case tok.text == ")":
+			parens--
+		case tok.text == "<":
+			angles++
+		case isClosingAngles(tok.text):
+			angles -= len(tok.text)
+		case parens > 0 || angles > 0:
+			// the primary constructor, the type parameters or the type arguments
+		case kotlin && tok.text == ":":
+			collecting = true
+		case kotlin && (tok.text == "fun" || tok.text == "val" || tok.text == "var" || tok.text == "where" || hasS(typeDeclarationKeywords, tok.text)):
+			// the end of a Kotlin class without a body
+			return names
+		case !kotlin && (tok.text == "extends" || tok.text == "implements"):
+			collecting = true
+		case !kotlin && tok.text == "permits":
+			collecting = false
+		case collecting && tok.kind == tokenIdentifier && tokens[j-1].text != "@":
+			if r, _ := utf8.DecodeRuneInString(tok.text); unicode.IsUpper(r) && !hasS(names, tok.text) {
+				names = append(names, tok.text)
+			}
+		}
+	}
+	return names
+}
+
+// anonymousClassType returns the name of the type that an anonymous Java class extends or implements, like "Runnable"
+// for "new Runnable() {", given the position of the "{" operator, or an empty string if it is not an anonymous class
+func anonymousClassType(tokens []token, i int) string {
+	j := i - 1
+	if j < 0 || tokens[j].text != ")" {
+		return ""
+	}
+	// Skip the arguments, then the type arguments, like "<>" in "new Comparator<>() {"
+	for depth := 0; j >= 0; j-- {
+		if tokens[j].text == ")" {
+			depth++
+		} else if tokens[j].text == "(" {
+			if depth--; depth == 0 {
+				break
+			}
+		}
+	}
+	j--
+	if j >= 0 && isClosingAngles(tokens[j].text) {
+		for depth := 0; j >= 0; j-- {
+			if isClosingAngles(tokens[j].text) {
+				depth += len(tokens[j].text)
+			} else if tokens[j].text == "<" {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		j--
+	}
+	if j < 0 || tokens[j].kind != tokenIdentifier {
+		return ""
+	}
+	name := tokens[j].text
+	for j >= 2 && tokens[j-1].text == "." && tokens[j-2].kind == tokenIdentifier {
+		j -= 2
+	}
+	if j == 0 || tokens[j-1].text != "new" {
+		return ""
+	}
+	return name
+}
+
+// parseSource tokenizes the given Java or Kotlin source code, and finds the package name,
+// the import statements, the declared types and the identifiers that may refer to types.
+// Identifiers that start with an uppercase letter are considered to be type names, unless they
+// are part of a qualified name (like "Entry" in "Map.Entry"), or of a package or import statement.
+// If the source code can not be fully tokenized, the error is returned together with what was found.
+func parseSource(data []byte, kotlin bool) (*parsedSource, error) {
+	tokens, err := tokenize(data, kotlin)
+	src := &parsedSource{identifiers: make(map[string]bool), declaredNames: make(map[string]bool)}
+	depth := 0
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if tok.kind == tokenOperator {
+			switch tok.text {
+			case "{":
+				depth++
+				if !kotlin {
+					if name := anonymousClassType(tokens, i); name != "" && !hasS(src.supertypes, name) {
+						src.supertypes = append(src.supertypes, name)
+					}
+				}
+			case "}":
+				if depth > 0 {
+					depth--
+				}
+			case "->":
+				if kotlin {
+					markLambdaParameters(tokens, i, src.declaredNames)
+				}
+			}
+			continue
+		}
+		if tok.kind != tokenIdentifier {
+			continue
+		}
+		var prev string
+		if i > 0 {
+			prev = tokens[i-1].text
+		}
+		isStatement := (tok.text == "package" || tok.text == "import") && prev != "." && prev != "@"
+		if !isStatement {
+			src.identifiers[tok.text] = true
+		}
+		switch {
+		case isStatement:
+			// Collect the qualified name, until ";" or the end of the line
+			var (
+				path   strings.Builder
+				static bool
+				alias  string
+				j      = i + 1
+			)
+			for ; j < len(tokens) && tokens[j].line == tok.line && tokens[j].text != ";"; j++ {
+				switch {
+				case tokens[j].text == "static" && path.Len() == 0:
+					static = true
+				case tokens[j].text == "as" && kotlin && j+1 < len(tokens):
+					alias = tokens[j+1].text
+					j++
+				default:
+					path.WriteString(tokens[j].text)
+				}
+			}
+			if tok.text == "package" {
+				src.packageName = path.String()
+			} else {
+				src.imports = append(src.imports, importStatement{path: path.String(), static: static, alias: alias, line: tok.line})
+			}
+			i = j - 1
+		case hasS(typeDeclarationKeywords, tok.text) && prev != "." && prev != "::":
+			if tok.text != "typealias" && (i+1 >= len(tokens) || tokens[i+1].text != "class") {
+				for _, name := range supertypeNames(tokens, i, kotlin) {
+					if !hasS(src.supertypes, name) {
+						src.supertypes = append(src.supertypes, name)
+					}
+				}
+			}
+			// The next identifier is the name of the declared type, except for "enum class" in Kotlin,
+			// "companion object" without a name and object expressions like "object : Runnable"
+			if i+1 < len(tokens) && tokens[i+1].kind == tokenIdentifier && tokens[i+1].text != "class" {
+				typeName := tokens[i+1].text
+				if !hasS(src.declaredTypes, typeName) {
+					src.declaredTypes = append(src.declaredTypes, typeName)
+				}
+				if depth == 0 && !hasS(src.topLevelTypes, typeName) {
+					src.topLevelTypes = append(src.topLevelTypes, typeName)
+				}
+				i++
+			}
+		case prev == "." || prev == "?.":
+			// part of a qualified name or a member access
+		default:
+			r, _ := utf8.DecodeRuneInString(tok.text)
+			switch {
+			case unicode.IsUpper(r):
+				src.typeNames = append(src.typeNames, tok)
+				if qualifiedName := qualifiedTypeName(tokens, i); qualifiedName != tok.text {
+					src.qualifiedNames = append(src.qualifiedNames, token{kind: tokenIdentifier, text: qualifiedName, line: tok.line, offset: tok.offset})
+				}
+			case !unicode.IsLower(r) || keywords[tok.text] || prev == "::" || prev == "@":
+				// not a name, or a reference or a label like "return@forEach"
+			case isDeclaredName(tokens, i, kotlin):
+				src.declaredNames[tok.text] = true
+			default:
+				var next string
+				if i+1 < len(tokens) {
+					next = tokens[i+1].text
+				}
+				switch {
+				case next == "(" || (kotlin && next == "{"):
+					src.calls = append(src.calls, tok)
+				case kotlin && next != "=" && next != "->" && next != ",":
+					// Lambda parameters and named arguments are not references
+					src.references = append(src.references, tok)
+				}
+			}
+		}
+	}
+	return src, err
+}
@@ -0,0 +1,270 @@
+package autoimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// buildFilenames are the names of the supported build files, in order of preference
+var buildFilenames = []string{"pom.xml", "build.gradle.kts", "build.gradle"}
+
+// Dependency is a dependency that is declared in a pom.xml or build.gradle(.kts) file
+type Dependency struct {
+	Group    string // for instance "org.springframework"
+	Artifact string // for instance "spring-core"
+	Version  string // for instance "6.0.9", or empty if the version is not declared
+}
+
+// String returns the dependency coordinates, like "org.springframework:spring-core:6.0.9"
+func (dep Dependency) String() string {
+	if dep.Version == "" {
+		return dep.Group + ":" + dep.Artifact
+	}
+	return dep.Group + ":" + dep.Artifact + ":" + dep.Version
+}
+
+// FindBuildFile searches the directory of the given source file (or the given directory), and then each parent directory,
+// for a pom.xml, build.gradle.kts or build.gradle file. Returns an empty string if none is found.
+func FindBuildFile(sourceFilename string) string {
+	absPath, err := filepath.Abs(sourceFilename)
+	if err != nil {
+		return ""
+	}
+	dir := filepath.Dir(absPath)
+	if isDir(absPath) {
+		dir = absPath
+	}
+	for ; ; dir = filepath.Dir(dir) {
+		for _, buildFilename := range buildFilenames {
+			if buildFile := filepath.Join(dir, buildFilename); exists(buildFile) {
+				return buildFile
+			}
+		}
+		if parent := filepath.Dir(dir); parent == dir {
+			break
+		}
+	}
+	return ""
+}
+
+// ReadDependencies reads the declared dependencies from the given pom.xml, build.gradle or build.gradle.kts file
+func ReadDependencies(buildFile string) ([]Dependency, error) {
+	data, err := os.ReadFile(buildFile)
+	if err != nil {
+		return nil, err
+	}
+	if filepath.Base(buildFile) == "pom.xml" {
+		return parsePOM(data)
+	}
+	properties := make(map[string]string)
+	// gradle.properties in the same directory may define versions
+	if propertiesData, err := os.ReadFile(filepath.Join(filepath.Dir(buildFile), "gradle.properties")); err == nil {
+		ForEachLineInData(propertiesData, func(_, trimmedLine string) {
+			if strings.HasPrefix(trimmedLine, "#") || !strings.Contains(trimmedLine, "=") {
+				return // continue
+			}
+			fields := strings.SplitN(trimmedLine, "=", 2)
+			properties[strings.TrimSpace(fields[0])] = strings.TrimSpace(fields[1])
+		})
+	}
+	return parseGradle(data, properties), nil
+}
+
+// pomDependency is a <dependency> element in a pom.xml file
+type pomDependency struct {
+	GroupID    string `xml:"groupId"`
+	ArtifactID string `xml:"artifactId"`
+	Version    string `xml:"version"`
+	Scope      string `xml:"scope"`
+}
+
+// pomProject is the <project> element in a pom.xml file
+type pomProject struct {
+	GroupID string `xml:"groupId"`
+	Version string `xml:"version"`
+	Parent  struct {
+		GroupID string `xml:"groupId"`
+		Version string `xml:"version"`
+	} `xml:"parent"`
+	Properties struct {
+		Entries []struct {
+			XMLName xml.Name
+			Value   string `xml:",chardata"`
+		} `xml:",any"`
+	} `xml:"properties"`
+	Dependencies         []pomDependency `xml:"dependencies>dependency"`
+	DependencyManagement []pomDependency `xml:"dependencyManagement>dependencies>dependency"`
+}
+
+// propertyRegexp matches ${name} in pom.xml files and ${name} or $name in Gradle build files
+var propertyRegexp = regexp.MustCompile(`\$\{([\w.\-]+)\}|\$([A-Za-z_]\w*)`)
+
+// expandProperties replaces ${name} and $name with the values of the given properties
+func expandProperties(s string, properties map[string]string) string {
+	return propertyRegexp.ReplaceAllStringFunc(s, func(match string) string {
+		name := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(match, "$"), "{"), "}")
+		if value, ok := properties[name]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// parsePOM reads the dependencies from the contents of a pom.xml file.
+// Versions that are given as properties are expanded, and versions that are not given are
+// taken from <dependencyManagement>, if possible. Dependencies with the "system" or "import"
+// scope are skipped, since they are not found in the local repository.
+func parsePOM(data []byte) ([]Dependency, error) {
+	var project pomProject
+	if err := xml.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("could not parse pom.xml: %v", err)
+	}
+	properties := map[string]string{
+		"project.groupId": project.GroupID,
+		"project.version": project.Version,
+	}
+	if project.GroupID == "" {
+		properties["project.groupId"] = project.Parent.GroupID
+	}
+	if project.Version == "" {
+		properties["project.version"] = project.Parent.Version
+	}
+	for _, entry := range project.Properties.Entries {
+		properties[entry.XMLName.Local] = strings.TrimSpace(entry.Value)
+	}
+	managedVersions := make(map[string]string)
+	for _, dep := range project.DependencyManagement {
+		managedVersions[dep.GroupID+":"+dep.ArtifactID] = dep.Version
+	}
+	var deps []Dependency
+	for _, dep := range project.Dependencies {
+		if dep.Scope == "system" || dep.Scope == "import" {
+			continue
+		}
+		version := dep.Version
+		if version == "" {
+			version = managedVersions[dep.GroupID+":"+dep.ArtifactID]
+		}
+		deps = append(deps, Dependency{
+			Group:    expandProperties(strings.TrimSpace(dep.GroupID), properties),
+			Artifact: expandProperties(strings.TrimSpace(dep.ArtifactID), properties),
+			Version:  expandProperties(strings.TrimSpace(version), properties),
+		})
+	}
+	return deps, nil
+}
+
+var (
+	// gradleAssignmentRegexp matches variable assignments like: val springVersion = "6.0.9"
+	gradleAssignmentRegexp = regexp.MustCompile(`^(?:val|var|def|ext\.)?\s*([A-Za-z_][\w.]*)\s*=\s*["']([^"']+)["']`)
+	// gradleStringRegexp matches dependencies like: implementation("org.springframework:spring-core:6.0.9")
+	gradleStringRegexp = regexp.MustCompile(`^(\w+)\s*\(?\s*(?:platform\()?\s*["']([\w.\-]+):([\w.\-]+)(?::([^"':@]+))?(?::[^"'@]*)?(?:@\w+)?["']`)
+	// gradleMapRegexp matches dependencies like: implementation group: 'org.springframework', name: 'spring-core', version: '6.0.9'
+	gradleMapRegexp = regexp.MustCompile(`^(\w+)\s*\(?\s*group\s*[:=]\s*["']([^"']+)["']\s*,\s*name\s*[:=]\s*["']([^"']+)["'](?:\s*,\s*version\s*[:=]\s*["']([^"']+)["'])?`)
+)
+
+// gradleNonDependencies are method names that may look like dependency declarations, but are not
+var gradleNonDependencies = []string{"id", "classpath", "plugin", "apply", "include", "maven", "url"}
+
+// parseGradle reads the dependencies from the contents of a build.gradle or build.gradle.kts file.
+// Both the "group:artifact:version" notation and the group/name/version notation are supported.
+// Versions that are given as variables or properties are expanded.
+func parseGradle(data []byte, properties map[string]string) []Dependency {
+	var deps []Dependency
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		trimmedLine := strings.TrimSpace(scanner.Text())
+		if m := gradleAssignmentRegexp.FindStringSubmatch(trimmedLine); m != nil {
+			properties[strings.TrimPrefix(m[1], "ext.")] = m[2]
+			continue
+		}
+		m := gradleStringRegexp.FindStringSubmatch(trimmedLine)
+		if m == nil {
+			m = gradleMapRegexp.FindStringSubmatch(trimmedLine)
+		}
+		if m == nil || hasS(gradleNonDependencies, m[1]) {
+			continue
+		}
+		deps = append(deps, Dependency{
+			Group:    m[2],
+			Artifact: m[3],
+			Version:  expandProperties(m[4], properties),
+		})
+	}
+	return deps
+}
+
+// findDependencyJAR finds the .jar file for the given dependency in the local Maven repository
+// or the Gradle cache. If the declared version is not cached, the newest cached version is used.
+// Returns an empty string if no version of the dependency is cached.
+func findDependencyJAR(dep Dependency, mavenRepoPath, gradleCachePath string) string {
+	JARFilename := dep.Artifact + "-" + dep.Version + ".jar"
+	var mavenArtifactPath, gradleArtifactPath string
+	if mavenRepoPath != "" {
+		mavenArtifactPath = filepath.Join(mavenRepoPath, filepath.FromSlash(strings.ReplaceAll(dep.Group, ".", "/")), dep.Artifact)
+		if JARPath := filepath.Join(mavenArtifactPath, dep.Version, JARFilename); dep.Version != "" && exists(JARPath) {
+			return JARPath
+		}
+	}
+	if gradleCachePath != "" {
+		gradleArtifactPath = filepath.Join(gradleCachePath, dep.Group, dep.Artifact)
+		if dep.Version != "" {
+			if matches, _ := filepath.Glob(filepath.Join(gradleArtifactPath, dep.Version, "*", JARFilename)); len(matches) > 0 {
+				return matches[0]
+			}
+		}
+	}
+	// Fall back to the newest cached version
+	if mavenArtifactPath != "" && isDir(mavenArtifactPath) {
+		if JARPaths := LatestMavenJARs(mavenArtifactPath); len(JARPaths) > 0 {
+			return JARPaths[0]
+		}
+	}
+	if gradleArtifactPath != "" && isDir(gradleArtifactPath) {
+		if JARPaths := LatestGradleJARs(gradleArtifactPath); len(JARPaths) > 0 {
+			return JARPaths[0]
+		}
+	}
+	return ""
+}
+
+// ResolveClasspath finds the .jar file for each of the given dependencies in the local Maven repository
+// and the Gradle cache, without using the network. Dependencies where no version is cached are
+// returned as missing.
+func ResolveClasspath(deps []Dependency) ([]string, []Dependency) {
+	mavenRepoPath, _ := FindMavenRepository()
+	gradleCachePath, _ := FindGradleCache()
+	var (
+		JARPaths []string
+		missing  []Dependency
+	)
+	for _, dep := range deps {
+		JARPath := findDependencyJAR(dep, mavenRepoPath, gradleCachePath)
+		if JARPath == "" {
+			missing = append(missing, dep)
+			continue
+		}
+		if !hasS(JARPaths, JARPath) {
+			JARPaths = append(JARPaths, JARPath)
+		}
+	}
+	return JARPaths, missing
+}
+
+// NewProject creates a new ImportMatcher for the given source file. The dependencies that are
+// declared in the nearest pom.xml, build.gradle.kts or build.gradle file are searched, in addition
+// to the Java and Kotlin installations. Dependencies that are not found in the local caches are skipped.
+// The classes in the source tree of the project are also found, and have priority over library classes.
+// If no build file is found, only the Java and Kotlin installations are searched.
+// The optional settings are the same as for New. See also NewWithOptions.
+func NewProject(sourceFilename string, settings ...bool) (*ImportMatcher, error) {
+	opts := settingsOptions(settings...)
+	opts.ProjectFile = sourceFilename
+	return NewWithOptions(opts)
+}
@@ -0,0 +1,145 @@
+package autoimport
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPOM = `<?xml version="1.0" encoding="UTF-8"?>
+<project xmlns="http://maven.apache.org/POM/4.0.0">
+  <modelVersion>4.0.0</modelVersion>
+  <groupId>com.example</groupId>
+  <artifactId>demo</artifactId>
+  <version>1.0.0</version>
+  <properties>
+    <spring.version>6.0.9</spring.version>
+  </properties>
+  <dependencyManagement>
+    <dependencies>
+      <dependency>
+        <groupId>junit</groupId>
+        <artifactId>junit</artifactId>
+        <version>4.13.2</version>
+      </dependency>
+    </dependencies>
+  </dependencyManagement>
+  <dependencies>
+    <dependency>
+      <groupId>org.springframework</groupId>
+      <artifactId>spring-core</artifactId>
+      <version>${spring.version}</version>
+    </dependency>
+    <dependency>
+      <groupId>junit</groupId>
+      <artifactId>junit</artifactId>
+      <scope>test</scope>
+    </dependency>
+    <dependency>
+      <groupId>com.example</groupId>
+      <artifactId>not-cached</artifactId>
+      <version>1.0</version>
+    </dependency>
+  </dependencies>
+</project>
+`
+
+const testGradleBuild = `plugins {
+    id("org.springframework.boot") version "3.1.0"
+    kotlin("jvm") version "1.8.21"
+}
+
+val springVersion = "6.0.9"
+
+dependencies {
+    implementation("org.springframework:spring-core:$springVersion")
+    implementation(group = "org.springframework", name = "spring-web", version = "6.0.8")
+    testImplementation 'junit:junit:${junitVersion}'
+    implementation("org.jetbrains.kotlin:kotlin-reflect")
+}
+`
+
+func TestReadDependencies(t *testing.T) {
+	projectPath := t.TempDir()
+	pomPath := filepath.Join(projectPath, "pom.xml")
+	if err := os.WriteFile(pomPath, []byte(testPOM), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sourcePath := filepath.Join(projectPath, "src", "main", "java", "com", "example", "Main.java")
+	if buildFile := FindBuildFile(sourcePath); buildFile != pomPath {
+		t.Fatalf("Expected to find %s, got %q\n", pomPath, buildFile)
+	}
+	deps, err := ReadDependencies(pomPath)
+	if err != nil {
+		t.Fatalf("Could not read the dependencies: %v\n", err)
+	}
+	expected := []string{"org.springframework:spring-core:6.0.9", "junit:junit:4.13.2", "com.example:not-cached:1.0"}
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, deps)
+	}
+	for i, dep := range deps {
+		if dep.String() != expected[i] {
+			t.Errorf("Expected %s, got %s\n", expected[i], dep)
+		}
+	}
+
+	gradlePath := filepath.Join(projectPath, "gradle")
+	if err := os.MkdirAll(gradlePath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gradlePath, "build.gradle.kts"), []byte(testGradleBuild), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(gradlePath, "gradle.properties"), []byte("junitVersion=4.13.1\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	deps, err = ReadDependencies(filepath.Join(gradlePath, "build.gradle.kts"))
+	if err != nil {
+		t.Fatalf("Could not read the dependencies: %v\n", err)
+	}
+	expected = []string{"org.springframework:spring-core:6.0.9", "org.springframework:spring-web:6.0.8", "junit:junit:4.13.1", "org.jetbrains.kotlin:kotlin-reflect"}
+	if len(deps) != len(expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, deps)
+	}
+	for i, dep := range deps {
+		if dep.String() != expected[i] {
+			t.Errorf("Expected %s, got %s\n", expected[i], dep)
+		}
+	}
+}
+
+func TestResolveClasspath(t *testing.T) {
+	homePath := t.TempDir()
+	setenv(t, "HOME", homePath)
+	setenv(t, "GRADLE_USER_HOME", filepath.Join(homePath, ".gradle"))
+
+	repoPath := filepath.Join(homePath, ".m2", "repository")
+	springCorePath := filepath.Join(repoPath, "org", "springframework", "spring-core", "6.0.9", "spring-core-6.0.9.jar")
+	writeTestArchive(t, springCorePath, nil, "org/springframework/core/SpringVersion.class")
+	// only an older version of junit is cached
+	junitPath := filepath.Join(repoPath, "junit", "junit", "4.12", "junit-4.12.jar")
+	writeTestArchive(t, junitPath, nil, "org/junit/Assert.class")
+	gradleCachePath := filepath.Join(homePath, ".gradle", "caches", "modules-2", "files-2.1")
+	springWebPath := filepath.Join(gradleCachePath, "org.springframework", "spring-web", "6.0.8", "abc123", "spring-web-6.0.8.jar")
+	writeTestArchive(t, springWebPath, nil, "org/springframework/web/HttpRequestHandler.class")
+
+	deps := []Dependency{
+		{"org.springframework", "spring-core", "6.0.9"},
+		{"org.springframework", "spring-web", "6.0.8"},
+		{"junit", "junit", "4.13.2"},
+		{"com.example", "not-cached", "1.0"},
+	}
+	JARPaths, missing := ResolveClasspath(deps)
+	expected := []string{springCorePath, springWebPath, junitPath}
+	if len(JARPaths) != len(expected) {
+		t.Fatalf("Expected %v, got %v\n", expected, JARPaths)
+	}
+	for i := range JARPaths {
+		if JARPaths[i] != expected[i] {
+			t.Errorf("Expected %s, got %s\n", expected[i], JARPaths[i])
+		}
+	}
+	if len(missing) != 1 || missing[0].Artifact != "not-cached" {
+		t.Fatalf("Expected com.example:not-cached to be missing, got %v\n", missing)
+	}
+}
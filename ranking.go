@@ -0,0 +1,170 @@
+package autoimport
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// RankContext is what is known about a source file and its project when the classes
+// with the same name are ranked. The maps are shared, and must not be modified.
+type RankContext struct {
+	PackageName    string          // the package of the source file, if declared
+	Imports        []string        // the packages that the source file already imports from, like "java.util"
+	ProjectImports map[string]int  // the number of source files in the project that import from each package
+	Dependencies   map[string]bool // the archives of the dependencies that are declared by the project
+}
+
+// Ranker scores the classes that can be imported for a class name in a source file.
+// Classes with higher scores are preferred. Classes with the same score keep the default
+// order, where project classes come first and shorter class paths are preferred.
+type Ranker interface {
+	Score(info ClassInfo, ctx *RankContext) int
+}
+
+// DefaultRanker is the Ranker that is used when no other Ranker is set.
+// Classes in packages that the file already imports from score the highest, then classes in packages
+// that are imported by other files in the project, and then classes in related packages, that share at least
+// the first two components with the package of the file or with one of its imports. Project classes score
+// higher than classes in the declared dependencies, which score higher than JDK classes, where "java." packages
+// and the java.base module are preferred. Deprecated classes, and classes in deprecated modules, score lower,
+// and classes in internal packages, like "sun.", score the lowest.
+type DefaultRanker struct{}
+
+const (
+	importedPackageScore = 100
+	projectImportScore   = 50 // plus the number of files that import from the package, up to maxProjectImports
+	maxProjectImports    = 10
+	projectClassScore    = 50
+	dependencyScore      = 40
+	jdkScore             = 20
+	corePackageScore     = 5  // for JDK classes in "java." packages
+	javaBaseScore        = 5  // for JDK classes in the java.base module
+	relatedPackageScore  = 10 // per shared package component, for at least two shared components
+	deprecatedPenalty    = 100
+	internalPenalty      = 200
+)
+
+// Score returns the score of the given class for the source file that is described by the given context
+func (DefaultRanker) Score(info ClassInfo, ctx *RankContext) int {
+	packageName := strings.TrimSuffix(starPathOf(info.Path), ".*")
+	if info.Nested != "" {
+		packageName = strings.TrimSuffix(info.Path, "."+info.Nested)
+	}
+	score := 0
+	if hasS(ctx.Imports, packageName) {
+		score += importedPackageScore
+	} else {
+		shared := sharedComponents(packageName, ctx.PackageName)
+		for _, importedPackage := range ctx.Imports {
+			if n := sharedComponents(packageName, importedPackage); n > shared {
+				shared = n
+			}
+		}
+		if shared >= 2 {
+			score += relatedPackageScore * shared
+		}
+	}
+	if count := ctx.ProjectImports[packageName]; count > 0 {
+		if count > maxProjectImports {
+			count = maxProjectImports
+		}
+		score += projectImportScore + count
+	}
+	switch {
+	case info.Project:
+		score += projectClassScore
+	case ctx.Dependencies[info.Archive]:
+		score += dependencyScore
+	case isJDKClass(info):
+		score += jdkScore
+		if strings.HasPrefix(info.Path, "java.") {
+			score += corePackageScore
+		}
+		if info.Module == "java.base" {
+			score += javaBaseScore
+		}
+	}
+	if info.Deprecated {
+		score -= deprecatedPenalty
+	}
+	if isInternal(info.Path) {
+		score -= internalPenalty
+	}
+	return score
+}
+
+// sharedComponents returns the number of leading package components that the two package names have in common,
+// for instance 2 for "com.example.model" and "com.example.service"
+func sharedComponents(a, b string) int {
+	if a == "" || b == "" {
+		return 0
+	}
+	aFields, bFields := strings.Split(a, "."), strings.Split(b, ".")
+	n := 0
+	for n < len(aFields) && n < len(bFields) && aFields[n] == bFields[n] {
+		n++
+	}
+	return n
+}
+
+// isJDKClass checks if the given class was found in the JDK, either in a module
+// or in the rt.jar or src.zip file of an older JDK
+func isJDKClass(info ClassInfo) bool {
+	if info.Module != "" {
+		return true
+	}
+	base := filepath.Base(info.Archive)
+	return base == "rt.jar" || base == "src.zip"
+}
+
+// importedPackages returns the packages that the source code imports classes from, like "java.util"
+// for both "java.util.*" and "java.util.List". Static imports are not included.
+func (src *parsedSource) importedPackages() []string {
+	var packages []string
+	for _, stmt := range src.imports {
+		if stmt.static || stmt.path == "" {
+			continue
+		}
+		packageName := strings.TrimSuffix(starPathOf(stmt.path), ".*")
+		if strings.HasSuffix(stmt.path, ".*") {
+			packageName = strings.TrimSuffix(stmt.path, ".*")
+		}
+		if packageName != "*" && !hasS(packages, packageName) {
+			packages = append(packages, packageName)
+		}
+	}
+	return packages
+}
+
+// rankContext returns the context for ranking classes for the given source code
+func (ima *ImportMatcher) rankContext(src *parsedSource) *RankContext {
+	return &RankContext{
+		PackageName:    src.packageName,
+		Imports:        src.importedPackages(),
+		ProjectImports: ima.projectImports,
+		Dependencies:   ima.dependencyArchives,
+	}
+}
+
+// rankClassPaths returns the given class paths, ordered by the scores of the Ranker, best first.
+// Class paths with the same score keep their order.
+func (ima *ImportMatcher) rankClassPaths(classPaths []string, ctx *RankContext) []string {
+	ranker := ima.Ranker
+	if ranker == nil {
+		ranker = DefaultRanker{}
+	}
+	scores := make(map[string]int, len(classPaths))
+	for _, classPath := range classPaths {
+		info, ok := ima.Info(classPath)
+		if !ok {
+			info = ClassInfo{Path: classPath}
+		}
+		scores[classPath] = ranker.Score(info, ctx)
+	}
+	ranked := append([]string{}, classPaths...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] > scores[ranked[j]]
+	})
+	return ranked
+}
@@ -0,0 +1,180 @@
+package autoimport
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testDeprecatedClassFile returns a minimal .class file for the given class path (or "module-info"),
+// that is annotated with @Deprecated
+func testDeprecatedClassFile(classPath string, access uint16) []byte {
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.BigEndian, uint32(classMagic))
+	binary.Write(&buf, binary.BigEndian, uint32(53))
+	binary.Write(&buf, binary.BigEndian, uint16(7))
+	for _, s := range []string{strings.ReplaceAll(classPath, ".", "/"), "RuntimeVisibleAnnotations", "Ljava/lang/Deprecated;", "java/lang/Object"} {
+		buf.WriteByte(constantUtf8)
+		binary.Write(&buf, binary.BigEndian, uint16(len(s)))
+		buf.WriteString(s)
+	}
+	buf.WriteByte(constantClass)
+	binary.Write(&buf, binary.BigEndian, uint16(1))
+	buf.WriteByte(constantClass)
+	binary.Write(&buf, binary.BigEndian, uint16(4))
+	for _, u := range []uint16{access, 5, 6, 0, 0, 0, 1, 2} {
+		binary.Write(&buf, binary.BigEndian, u)
+	}
+	binary.Write(&buf, binary.BigEndian, uint32(6))
+	for _, u := range []uint16{1, 3, 0} {
+		binary.Write(&buf, binary.BigEndian, u)
+	}
+	return buf.Bytes()
+}
+
+func TestDefaultRanker(t *testing.T) {
+	var ranker DefaultRanker
+	swingTimer := ClassInfo{Path: "javax.swing.Timer", Module: "java.desktop"}
+	utilTimer := ClassInfo{Path: "java.util.Timer", Module: "java.base"}
+	metricsTimer := ClassInfo{Path: "com.codahale.metrics.Timer", Archive: "/deps/metrics-core.jar"}
+
+	ctx := &RankContext{PackageName: "com.example.service"}
+	if ranker.Score(utilTimer, ctx) <= ranker.Score(swingTimer, ctx) {
+		t.Errorf("Expected java.util.Timer to be preferred over javax.swing.Timer\n")
+	}
+	if ranker.Score(swingTimer, ctx) <= ranker.Score(metricsTimer, ctx) {
+		t.Errorf("Expected a JDK class to be preferred over a class that is not in a declared dependency\n")
+	}
+	ctx.Dependencies = map[string]bool{"/deps/metrics-core.jar": true}
+	if ranker.Score(metricsTimer, ctx) <= ranker.Score(utilTimer, ctx) {
+		t.Errorf("Expected a class in a declared dependency to be preferred over a JDK class\n")
+	}
+	ctx.Dependencies = nil
+	ctx.ProjectImports = map[string]int{"javax.swing": 3}
+	if ranker.Score(swingTimer, ctx) <= ranker.Score(utilTimer, ctx) {
+		t.Errorf("Expected a package that is imported elsewhere in the project to be preferred\n")
+	}
+	ctx.Imports = []string{"java.util"}
+	if ranker.Score(utilTimer, ctx) <= ranker.Score(swingTimer, ctx) {
+		t.Errorf("Expected a package that is imported by the file to be preferred\n")
+	}
+
+	related := ClassInfo{Path: "com.example.model.User"}
+	unrelated := ClassInfo{Path: "org.other.User"}
+	if ranker.Score(related, ctx) <= ranker.Score(unrelated, ctx) {
+		t.Errorf("Expected a class in a related package to be preferred\n")
+	}
+	deprecated := utilTimer
+	deprecated.Deprecated = true
+	if ranker.Score(deprecated, ctx) >= ranker.Score(swingTimer, ctx) {
+		t.Errorf("Expected a deprecated class to be ranked lower\n")
+	}
+	if ranker.Score(ClassInfo{Path: "sun.misc.Timer", Module: "java.base"}, &RankContext{}) >= ranker.Score(unrelated, &RankContext{}) {
+		t.Errorf("Expected an internal class to be ranked the lowest\n")
+	}
+}
+
+// swingRanker is a Ranker that prefers Swing classes
+type swingRanker struct{}
+
+func (swingRanker) Score(info ClassInfo, ctx *RankContext) int {
+	if strings.HasPrefix(info.Path, "javax.swing.") {
+		return 1
+	}
+	return 0
+}
+
+func TestRankedImports(t *testing.T) {
+	jdkPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(jdkPath, "jmods", "java.base.jmod"), jmodMagic, "classes/java/util/Timer.class")
+	writeTestArchive(t, filepath.Join(jdkPath, "jmods", "java.desktop.jmod"), jmodMagic, "classes/javax/swing/Timer.class")
+	writeTestJAR(t, filepath.Join(jdkPath, "metrics-core.jar"), map[string][]byte{
+		"com/codahale/metrics/Timer.class":             nil,
+		"com/codahale/metrics/MetricRegistry.class":    nil,
+		"org/legacy/remote/PortableRemoteObject.class": nil,
+		"com/codahale/metrics/Meter.class":             testDeprecatedClassFile("com.codahale.metrics.Meter", accPublic),
+	})
+	// The module descriptor of the java.corba module of JDK 9 and 10 is annotated with @Deprecated
+	corbaPath := filepath.Join(jdkPath, "jmods", "java.corba.jmod")
+	writeTestJAR(t, corbaPath, map[string][]byte{
+		"classes/module-info.class":                    testDeprecatedClassFile("module-info", accModule),
+		"classes/javax/rmi/PortableRemoteObject.class": testClassFile("javax.rmi.PortableRemoteObject", accPublic, 0, nil, nil),
+	})
+	data, err := os.ReadFile(corbaPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(corbaPath, append(jmodMagic, data...), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{jdkPath}, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if info, ok := ima.Info("javax.rmi.PortableRemoteObject"); !ok || !info.Deprecated {
+		t.Fatalf("Expected the classes of a deprecated module to be deprecated, got %+v\n", info)
+	}
+	if info, ok := ima.Info("com.codahale.metrics.Meter"); !ok || !info.Deprecated {
+		t.Fatalf("Expected a class that is annotated with @Deprecated to be deprecated, got %+v\n", info)
+	}
+
+	for _, test := range []struct {
+		source, expected string
+	}{
+		{"class Main {\n    Timer timer;\n}\n", "import java.util.*; // Timer"},
+		{"import com.codahale.metrics.MetricRegistry;\n\nclass Main {\n    MetricRegistry registry;\n    Timer timer;\n}\n", "import com.codahale.metrics.*; // MetricRegistry, Timer"},
+		{"class Main {\n    PortableRemoteObject remote;\n}\n", "import org.legacy.remote.*; // PortableRemoteObject"},
+	} {
+		importBlock, err := ima.ImportBlock([]byte(test.source), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(importBlock) != test.expected {
+			t.Errorf("Expected\n%s\ngot\n%s\n", test.expected, importBlock)
+		}
+	}
+
+	ima.Ranker = swingRanker{}
+	importBlock, err := ima.ImportBlock([]byte("class Main {\n    Timer timer;\n}\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "import javax.swing.*; // Timer"; string(importBlock) != expected {
+		t.Fatalf("Expected the custom Ranker to be used, got:\n%s\n", importBlock)
+	}
+}
+
+func TestProjectImports(t *testing.T) {
+	projectPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectPath, "pom.xml"), []byte("<project></project>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	packagePath := filepath.Join(projectPath, "src", "main", "java", "com", "example")
+	if err := os.MkdirAll(packagePath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ui := "package com.example;\n\nimport javax.swing.*;\n\npublic class Window {\n    Timer timer;\n}\n"
+	if err := os.WriteFile(filepath.Join(packagePath, "Window.java"), []byte(ui), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	jdkPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(jdkPath, "jmods", "java.base.jmod"), jmodMagic, "classes/java/util/Timer.class")
+	writeTestArchive(t, filepath.Join(jdkPath, "jmods", "java.desktop.jmod"), jmodMagic, "classes/javax/swing/Timer.class")
+
+	sourcePath := filepath.Join(packagePath, "Main.java")
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{jdkPath}, ProjectFile: sourcePath, CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	importBlock, err := ima.ImportBlock([]byte("package com.example;\n\nclass Main {\n    Timer timer;\n}\n"), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "import javax.swing.*; // Timer"; string(importBlock) != expected {
+		t.Fatalf("Expected the package that is imported by the project to be used, got:\n%s\n", importBlock)
+	}
+}
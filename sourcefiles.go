@@ -0,0 +1,178 @@
+package autoimport
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// skippedDirs are directories that are never searched for source files, like VCS directories
+var skippedDirs = []string{".git", ".gradle", ".hg", ".idea", ".svn", "node_modules"}
+
+// buildOutputDirs are build output directories, that are only skipped when they are in the searched directory
+// or next to a build file, so that packages with the same name, like "com/acme/build", are still searched
+var buildOutputDirs = []string{"build", "out", "target"}
+
+// ignoreFiles are files with patterns for files and directories that should be skipped
+var ignoreFiles = []string{".gitignore", ".autoimportignore"}
+
+// ignorePattern is a pattern from a .gitignore or .autoimportignore file
+type ignorePattern struct {
+	dir      string // the directory that contains the ignore file
+	pattern  string
+	anchored bool // the pattern contains a "/", and is matched against the path relative to dir
+	dirOnly  bool // the pattern ends with a "/", and only matches directories
+}
+
+// readIgnorePatterns reads the ignore patterns in the given directory, if any.
+// Negated patterns (starting with "!") are not supported, and are skipped.
+func readIgnorePatterns(dir string) []ignorePattern {
+	var patterns []ignorePattern
+	for _, ignoreFile := range ignoreFiles {
+		f, err := os.Open(filepath.Join(dir, ignoreFile))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+				continue
+			}
+			p := ignorePattern{dir: dir}
+			if strings.HasSuffix(line, "/") {
+				p.dirOnly = true
+				line = strings.TrimSuffix(line, "/")
+			}
+			line = strings.TrimPrefix(line, "**/")
+			if strings.Contains(line, "/") {
+				p.anchored = true
+				line = strings.TrimPrefix(line, "/")
+			}
+			p.pattern = line
+			patterns = append(patterns, p)
+		}
+		f.Close()
+	}
+	return patterns
+}
+
+// parentIgnorePatterns reads the ignore patterns in the parent directories of the given directory,
+// up to the root of the repository it is in, so that they also apply when a subdirectory is searched
+func parentIgnorePatterns(dir string) []ignorePattern {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil
+	}
+	var patterns []ignorePattern
+	for !exists(filepath.Join(dir, ".git")) {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+		patterns = append(patterns, readIgnorePatterns(dir)...)
+	}
+	return patterns
+}
+
+// matches checks if the given path is matched by the ignore pattern.
+// Only paths below the directory of the ignore file can match.
+func (p ignorePattern) matches(path string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if filepath.IsAbs(p.dir) && !filepath.IsAbs(path) {
+		// The patterns from parent directories have absolute paths
+		if absPath, err := filepath.Abs(path); err == nil {
+			path = absPath
+		}
+	}
+	rel, err := filepath.Rel(p.dir, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	name := filepath.Base(path)
+	if p.anchored {
+		name = filepath.ToSlash(rel)
+	}
+	matched, _ := filepath.Match(p.pattern, name)
+	return matched
+}
+
+// isBuildOutputDir checks if the given directory is a build output directory, like "target",
+// that is directly within the given root directory, or next to a build file
+func isBuildOutputDir(dir, root string) bool {
+	if !hasS(buildOutputDirs, filepath.Base(dir)) {
+		return false
+	}
+	parent := filepath.Dir(dir)
+	if parent == filepath.Clean(root) {
+		return true
+	}
+	for _, buildFilename := range buildFilenames {
+		if exists(filepath.Join(parent, buildFilename)) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSourceFile checks if the given filename has a .java or .kt extension
+func isSourceFile(filename string) bool {
+	ext := filepath.Ext(filename)
+	return ext == ".java" || ext == ".kt"
+}
+
+// FindSourceFiles returns the given files, and all .java and .kt files within the given directories.
+// Build directories (like "build" and "target"), VCS directories (like ".git") and files and
+// directories that are matched by patterns in .gitignore or .autoimportignore files are skipped.
+// The ignore files in the parent directories of a given directory are also used, up to the repository root.
+func FindSourceFiles(paths ...string) ([]string, error) {
+	var sourceFiles []string
+	for _, path := range paths {
+		fi, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !fi.IsDir() {
+			// Files that are given directly are never skipped
+			sourceFiles = append(sourceFiles, path)
+			continue
+		}
+		patterns := parentIgnorePatterns(path)
+		err = filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			isDir := info.IsDir()
+			if filePath != path {
+				if isDir && (hasS(skippedDirs, info.Name()) || isBuildOutputDir(filePath, path)) {
+					return filepath.SkipDir
+				}
+				for _, p := range patterns {
+					if p.matches(filePath, isDir) {
+						if isDir {
+							return filepath.SkipDir
+						}
+						return nil
+					}
+				}
+			}
+			if isDir {
+				// The patterns of an ignore file apply to everything below the directory it is in
+				patterns = append(patterns, readIgnorePatterns(filePath)...)
+				return nil
+			}
+			if isSourceFile(filePath) {
+				sourceFiles = append(sourceFiles, filePath)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sourceFiles, nil
+}
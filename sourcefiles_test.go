@@ -0,0 +1,93 @@
+package autoimport
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestFindSourceFiles(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"pom.xml",
+		"src/main/java/com/example/Main.java",
+		"src/main/kotlin/com/example/Util.kt",
+		"src/main/generated/Generated.java",
+		"src/main/java/com/example/Ignored.java",
+		"src/main/java/com/example/build/Builder.java",
+		"src/main/java/com/example/target/Target.java",
+		"src/main/java/com/example/generated/Generated.java",
+		"module/build.gradle",
+		"module/build/generated/Generated.java",
+		"module/src/Module.java",
+		"target/classes/Main.java",
+		"build/Main.kt",
+		".git/Main.java",
+		"notes.txt",
+	}
+	for _, filename := range files {
+		path := filepath.Join(root, filepath.FromSlash(filename))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte{}, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(root, ".gitignore"), []byte("# generated code\ngenerated/\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "src", "main", "java", ".autoimportignore"), []byte("com/example/Ignored.java\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	explicitFile := filepath.Join(root, "target", "classes", "Main.java")
+	sourceFiles, err := FindSourceFiles(root, explicitFile)
+	if err != nil {
+		t.Fatalf("Could not find the source files: %v\n", err)
+	}
+	var got []string
+	for _, sourceFile := range sourceFiles {
+		rel, _ := filepath.Rel(root, sourceFile)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	expected := []string{
+		"module/src/Module.java",
+		"src/main/java/com/example/Main.java",
+		"src/main/java/com/example/build/Builder.java",
+		"src/main/java/com/example/target/Target.java",
+		"src/main/kotlin/com/example/Util.kt",
+		"target/classes/Main.java",
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v, got %v\n", expected, got)
+	}
+
+	// The ignore files in the parent directories are used when a subdirectory is searched,
+	// up to the repository root, which is the directory with the .git directory
+	sourceFiles, err = FindSourceFiles(filepath.Join(root, "src", "main", "java", "com"))
+	if err != nil {
+		t.Fatalf("Could not find the source files: %v\n", err)
+	}
+	got = got[:0]
+	for _, sourceFile := range sourceFiles {
+		rel, _ := filepath.Rel(root, sourceFile)
+		got = append(got, filepath.ToSlash(rel))
+	}
+	sort.Strings(got)
+	expected = []string{
+		"src/main/java/com/example/Main.java",
+		"src/main/java/com/example/build/Builder.java",
+		"src/main/java/com/example/target/Target.java",
+	}
+	if strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected %v, got %v\n", expected, got)
+	}
+
+	if _, err := FindSourceFiles(filepath.Join(root, "missing")); err == nil {
+		t.Fatalf("Expected an error for a path that does not exist\n")
+	}
+}
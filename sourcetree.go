@@ -0,0 +1,152 @@
+package autoimport
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// projectSourceDirs are the directories within a project that contains source code
+var projectSourceDirs = []string{
+	filepath.Join("src", "main", "java"),
+	filepath.Join("src", "main", "kotlin"),
+}
+
+// ProjectSourcePaths returns the source trees of the project that the given
+// source file (or directory) belongs to, like "src/main/java" and "src/main/kotlin".
+// The project directory is the directory of the nearest pom.xml or build.gradle(.kts) file.
+func ProjectSourcePaths(sourceFilename string) []string {
+	buildFile := FindBuildFile(sourceFilename)
+	if buildFile == "" {
+		return []string{}
+	}
+	projectPath := filepath.Dir(buildFile)
+	var sourcePaths []string
+	for _, sourceDir := range projectSourceDirs {
+		if sourcePath := filepath.Join(projectPath, sourceDir); isDir(sourcePath) {
+			sourcePaths = append(sourcePaths, sourcePath)
+		}
+	}
+	return sourcePaths
+}
+
+// sourceDeclarations returns the package name and the names of the top-level types
+// that are declared in the given Java or Kotlin source code, and the packages that are imported from
+func sourceDeclarations(data []byte, kotlin bool) (string, []string, []string) {
+	// Use what can be found, even if the source code can not be fully tokenized
+	src, _ := parseSource(data, kotlin)
+	return src.packageName, src.topLevelTypes, src.importedPackages()
+}
+
+// findClassesInSourceTree will search the given source tree for .java and .kt files,
+// and send the top-level types that are declared in each file to the found chan.
+// The packages that are imported from are counted, for ranking the classes with the same name.
+func (ima *ImportMatcher) findClassesInSourceTree(sourcePath string, found chan ClassInfo) {
+	filepath.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() || !isSourceFile(path) {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		packageName, typeNames, importedPackages := sourceDeclarations(data, filepath.Ext(path) == ".kt")
+		ima.mut.Lock()
+		for _, importedPackage := range importedPackages {
+			ima.projectImports[importedPackage]++
+		}
+		ima.mut.Unlock()
+		for _, typeName := range typeNames {
+			classPath := typeName
+			if packageName != "" {
+				classPath = packageName + "." + typeName
+			}
+			found <- ClassInfo{Path: classPath, Archive: path, Project: true}
+		}
+		return nil
+	})
+}
+
+// ReloadSources returns an ImportMatcher that shares the classes in the archives with this ImportMatcher,
+// but where the project source trees in SourcePaths have been searched again, so that classes that have been
+// added to, or removed from, the project are found. This ImportMatcher is not changed, and can still be used.
+func (ima *ImportMatcher) ReloadSources() *ImportMatcher {
+	language := Kotlin
+	if ima.onlyJava {
+		language = Java
+	}
+	reloaded := ima.ForLanguage(language)
+
+	// Copy the classes that were not found in the project source trees,
+	// including the classes in the Kotlin installation that are left out for Java files
+	ima.mut.RLock()
+	classMap, classInfo, memberMap := ima.classMap, ima.classInfo, ima.memberMap
+	if ima.shared != nil {
+		classMap, classInfo, memberMap = ima.shared.classMap, ima.shared.classInfo, ima.shared.memberMap
+	}
+	reloaded.classMap = make(map[string][]string, len(classMap))
+	reloaded.classInfo = make(map[string]ClassInfo, len(classInfo))
+	reloaded.memberMap = make(map[string][]memberRef, len(memberMap))
+	for classPath, info := range classInfo {
+		if !info.Project {
+			reloaded.classInfo[classPath] = info
+		}
+	}
+	for className, classPaths := range classMap {
+		var libraryClassPaths []string
+		for _, classPath := range classPaths {
+			if _, ok := reloaded.classInfo[classPath]; ok {
+				libraryClassPaths = append(libraryClassPaths, classPath)
+			}
+		}
+		if len(libraryClassPaths) > 0 {
+			reloaded.classMap[className] = libraryClassPaths
+		}
+	}
+	for name, refs := range memberMap {
+		reloaded.memberMap[name] = append([]memberRef{}, refs...)
+	}
+	ima.mut.RUnlock()
+	reloaded.projectImports = make(map[string]int)
+
+	found := make(chan ClassInfo)
+	done := make(chan bool)
+	go func() {
+		for _, sourcePath := range reloaded.SourcePaths {
+			reloaded.findClassesInSourceTree(sourcePath, found)
+		}
+		close(found)
+	}()
+	go reloaded.consumeClasses(found, done)
+	<-done
+
+	if ima.shared != nil {
+		reloaded.shared = &sharedIndex{classMap: reloaded.classMap, classInfo: reloaded.classInfo, memberMap: reloaded.memberMap}
+		if reloaded.onlyJava {
+			java := reloaded.shared.forJava(reloaded.kotlinPath)
+			reloaded.classMap, reloaded.classInfo, reloaded.memberMap = java.classMap, java.classInfo, java.memberMap
+		}
+	}
+	return reloaded
+}
+
+// SourcesModTime returns the latest modification time of the project source trees in SourcePaths,
+// and of the directories and .java and .kt files within them, for finding out if ReloadSources is needed
+func (ima *ImportMatcher) SourcesModTime() time.Time {
+	var latest time.Time
+	for _, sourcePath := range ima.SourcePaths {
+		filepath.Walk(sourcePath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return nil
+			}
+			if (info.IsDir() || isSourceFile(path)) && info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+			return nil
+		})
+	}
+	return latest
+}
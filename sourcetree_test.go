@@ -0,0 +1,171 @@
+package autoimport
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSourceDeclarations(t *testing.T) {
+	const kotlinSource = `package com.ostekake.trust.feedback
+
+import java.util.*
+
+/** A class Commented in a comment */
+data class Feedback(val text: String = "class NotAClass") {
+    companion object {
+        class Nested
+    }
+}
+
+enum class Rating { GOOD, BAD }
+
+object PackageMarker
+
+typealias Ratings = List<Rating>
+
+fun interface Listener {
+    fun onFeedback(feedback: Feedback)
+}
+`
+	packageName, typeNames, importedPackages := sourceDeclarations([]byte(kotlinSource), true)
+	if packageName != "com.ostekake.trust.feedback" {
+		t.Fatalf("Expected the com.ostekake.trust.feedback package, got %q\n", packageName)
+	}
+	expected := "Feedback,Rating,PackageMarker,Ratings,Listener"
+	if got := strings.Join(typeNames, ","); got != expected {
+		t.Fatalf("Expected %s, got %s\n", expected, got)
+	}
+	if len(importedPackages) != 1 || importedPackages[0] != "java.util" {
+		t.Fatalf("Expected the java.util package to be imported from, got %v\n", importedPackages)
+	}
+
+	const javaSource = `package com.example;
+
+@Retention(RetentionPolicy.RUNTIME)
+public @interface Marker {
+    String value() default "class Value";
+}
+
+record Point(int x, int y) {
+    enum Inner { A }
+}
+`
+	packageName, typeNames, importedPackages = sourceDeclarations([]byte(javaSource), false)
+	if packageName != "com.example" {
+		t.Fatalf("Expected the com.example package, got %q\n", packageName)
+	}
+	if got := strings.Join(typeNames, ","); got != "Marker,Point" {
+		t.Fatalf("Expected Marker,Point, got %s\n", got)
+	}
+	if len(importedPackages) != 0 {
+		t.Fatalf("Expected no imported packages, got %v\n", importedPackages)
+	}
+}
+
+func TestProjectSourceTree(t *testing.T) {
+	projectPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectPath, "pom.xml"), []byte("<project></project>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	packagePath := filepath.Join(projectPath, "src", "main", "kotlin", "com", "ostekake", "trust", "feedback")
+	if err := os.MkdirAll(packagePath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(packagePath, "PackageMarker.kt"), []byte("package com.ostekake.trust.feedback\n\nobject PackageMarker\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sourcePath := filepath.Join(projectPath, "src", "main", "kotlin", "com", "ostekake", "Application.kt")
+	sourcePaths := ProjectSourcePaths(sourcePath)
+	if len(sourcePaths) != 1 || sourcePaths[0] != filepath.Join(projectPath, "src", "main", "kotlin") {
+		t.Fatalf("Expected the src/main/kotlin directory, got %v\n", sourcePaths)
+	}
+
+	// A library class with the same name should not win over the project class
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "lib.jar"), nil, "org/lib/PackageMarker.class")
+
+	ima, err := NewWithOptions(Options{Language: Kotlin, JARPaths: []string{libPath}, ProjectFile: sourcePath})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	if classPath := ima.ImportPathExact("PackageMarker"); classPath != "com.ostekake.trust.feedback.PackageMarker" {
+		t.Fatalf("Expected the project class to be preferred, got %q\n", classPath)
+	}
+	if classPaths := ima.ClassPaths("PackageMarker"); len(classPaths) != 2 {
+		t.Fatalf("Expected both the project class and the library class, got %v\n", classPaths)
+	}
+
+	// Classes in the same package should not be imported
+	importBlock, err := ima.ImportBlock([]byte("package com.ostekake.trust.feedback\n\nval marker = PackageMarker\n"), false)
+	if err != nil {
+		t.Fatalf("Could not generate the import block: %v\n", err)
+	}
+	if len(importBlock) != 0 {
+		t.Fatalf("Expected no imports for a class in the same package, got:\n%s\n", importBlock)
+	}
+	importBlock, err = ima.ImportBlock([]byte("package com.ostekake\n\nval marker = PackageMarker\n"), false)
+	if err != nil {
+		t.Fatalf("Could not generate the import block: %v\n", err)
+	}
+	if expected := "import com.ostekake.trust.feedback.*; // PackageMarker"; string(importBlock) != expected {
+		t.Fatalf("Expected %s, got:\n%s\n", expected, importBlock)
+	}
+}
+
+func TestReloadSources(t *testing.T) {
+	projectPath := t.TempDir()
+	if err := os.WriteFile(filepath.Join(projectPath, "pom.xml"), []byte("<project></project>"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	packagePath := filepath.Join(projectPath, "src", "main", "java", "com", "example")
+	if err := os.MkdirAll(packagePath, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeSource := func(name, source string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(packagePath, name), []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeSource("Service.java", "package com.example;\n\npublic class Service {}\n")
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "lib.jar"), nil, "org/lib/Widget.class")
+
+	ima, err := NewWithOptions(Options{Language: Java, JARPaths: []string{libPath}, ProjectFile: filepath.Join(packagePath, "Main.java"), CachePath: filepath.Join(t.TempDir(), "classes.gob")})
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	modTime := ima.SourcesModTime()
+	if modTime.IsZero() {
+		t.Fatalf("Expected the modification time of the source tree\n")
+	}
+
+	// A class is added and another one is removed
+	writeSource("Client.java", "package com.example;\n\nimport javax.swing.*;\n\npublic class Client {}\n")
+	if err := os.Remove(filepath.Join(packagePath, "Service.java")); err != nil {
+		t.Fatal(err)
+	}
+	later := modTime.Add(time.Second)
+	if err := os.Chtimes(packagePath, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if !ima.SourcesModTime().After(modTime) {
+		t.Fatalf("Expected the modification time to change\n")
+	}
+	reloaded := ima.ReloadSources()
+	for className, expected := range map[string]string{"Client": "com.example.Client", "Service": "", "Widget": "org.lib.Widget"} {
+		if classPath := reloaded.ImportPathExact(className); classPath != expected {
+			t.Errorf("Expected %q for %s after reloading, got %q\n", expected, className, classPath)
+		}
+	}
+	if reloaded.projectImports["javax.swing"] != 1 {
+		t.Errorf("Expected the imports of the project to be counted again, got %v\n", reloaded.projectImports)
+	}
+	// The original ImportMatcher is not changed
+	if ima.ImportPathExact("Service") != "com.example.Service" || ima.ImportPathExact("Client") != "" {
+		t.Errorf("Expected the original ImportMatcher to be unchanged\n")
+	}
+}
@@ -0,0 +1,64 @@
+package autoimport
+
+import (
+	"strings"
+)
+
+// kotlinOperatorNames are the names of Kotlin functions that can be used through operators or
+// property delegation, without the name appearing in the source code. Imports of these are never
+// considered to be unused.
+var kotlinOperatorNames = []string{
+	"getValue", "setValue", "provideDelegate", "invoke", "get", "set", "contains", "iterator",
+	"next", "hasNext", "compareTo", "equals", "plus", "minus", "times", "div", "rem", "rangeTo",
+	"rangeUntil", "unaryPlus", "unaryMinus", "not", "inc", "dec", "plusAssign", "minusAssign",
+	"timesAssign", "divAssign", "remAssign", "component1", "component2", "component3",
+	"component4", "component5",
+}
+
+// isUnusedImport checks if the given import statement is not used by the given source code.
+// Explicit imports are unused if the imported name does not appear in the source code.
+// Wildcard imports are unused if none of the used names are known classes in the imported package.
+// If none of the classes in the package are known, the wildcard import is kept, since it can not be checked.
+// Static wildcard imports are always kept.
+func (ima *ImportMatcher) isUnusedImport(stmt importStatement, src *parsedSource) bool {
+	if !strings.HasSuffix(stmt.path, ".*") {
+		name := stmt.alias
+		if name == "" {
+			name = classNameOf(stmt.path)
+		}
+		if !ima.onlyJava && hasS(kotlinOperatorNames, name) {
+			return false
+		}
+		return !src.identifiers[name]
+	}
+	if stmt.static {
+		return false
+	}
+	packageName := strings.TrimSuffix(stmt.path, ".*")
+	ima.mut.RLock()
+	defer ima.mut.RUnlock()
+	knownPackage := false
+	for className, classPaths := range ima.classMap {
+		for _, classPath := range classPaths {
+			if !strings.HasPrefix(classPath, packageName+".") || strings.Contains(classPath[len(packageName)+1:], ".") {
+				continue
+			}
+			if src.identifiers[className] {
+				return false
+			}
+			knownPackage = true
+		}
+	}
+	return knownPackage
+}
+
+// unusedImportLines returns the line numbers of the import statements in the given source code that are not used
+func (ima *ImportMatcher) unusedImportLines(src *parsedSource) map[int]bool {
+	unusedLines := make(map[int]bool)
+	for _, stmt := range src.imports {
+		if ima.isUnusedImport(stmt, src) {
+			unusedLines[stmt.line] = true
+		}
+	}
+	return unusedLines
+}
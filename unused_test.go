@@ -0,0 +1,81 @@
+package autoimport
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRemoveUnusedImports(t *testing.T) {
+	libPath := t.TempDir()
+	writeTestArchive(t, filepath.Join(libPath, "rt.jar"), nil,
+		"java/util/ArrayList.class",
+		"java/util/HashMap.class",
+		"java/io/File.class",
+		"java/io/Reader.class",
+		"javax/swing/JFrame.class",
+	)
+
+	const input = `package com.example;
+
+import java.io.*;
+import java.util.ArrayList;
+import java.util.HashMap;
+import javax.swing.*;
+import net.java.games.jogl.*;
+import static org.junit.Assert.assertEquals;
+import static org.junit.Assert.assertTrue;
+
+public class Main extends JFrame {
+    ArrayList<String> list = new ArrayList<>(); // HashMap
+    GLCanvas canvas;
+
+    void test() {
+        assertEquals("File", "HashMap");
+    }
+}
+`
+	const expected = `package com.example;
+
+import java.util.ArrayList;
+import javax.swing.*; // JFrame
+import net.java.games.jogl.*;
+import static org.junit.Assert.assertEquals;
+
+public class Main extends JFrame {
+    ArrayList<String> list = new ArrayList<>(); // HashMap
+    GLCanvas canvas;
+
+    void test() {
+        assertEquals("File", "HashMap");
+    }
+}
+`
+	const onlyJava, removeExistingImports = true, false
+	ima, err := NewCustom([]string{libPath}, onlyJava, removeExistingImports)
+	if err != nil {
+		t.Fatalf("Could not initialize ImportMatcher: %s\n", err)
+	}
+	ima.RemoveUnusedImports = true
+	got, err := ima.FixImports([]byte(input), false)
+	if err != nil {
+		t.Fatalf("Error processing FixImports: %v", err)
+	}
+	if string(got) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestWithoutExplicitImports(t *testing.T) {
+	explicitImports := map[string]bool{"java.util.ArrayList": true}
+	for importLine, expected := range map[string]string{
+		"import java.util.*; // ArrayList":          "",
+		"import java.util.*; // ArrayList, HashMap": "import java.util.*; // HashMap",
+		"import java.util.*;":                       "import java.util.*;",
+		"import java.io.*; // ArrayList":            "import java.io.*; // ArrayList",
+		"import java.util.ArrayList;":               "import java.util.ArrayList;",
+	} {
+		if got := withoutExplicitImports(importLine, explicitImports); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, importLine, got)
+		}
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
 )
 
@@ -28,12 +27,6 @@ func hasS(sl []string, e string) bool {
 	return false
 }
 
-// extractWords can extract words that starts with an uppercase letter from the given source code
-func extractWords(sourceCode string) []string {
-	re := regexp.MustCompile(`\b[A-Z][a-z]*([A-Z][a-z]*)*\b`)
-	return re.FindAllString(sourceCode, -1)
-}
-
 // isDir checks if the given path is a directory (could also be a symlink)
 func isDir(path string) bool {
 	fi, err := os.Stat(path)
@@ -52,6 +45,12 @@ func exists(path string) bool {
 	return err == nil
 }
 
+// isWithin checks if the given path is the given directory, or a path within it
+func isWithin(path, dir string) bool {
+	dir = strings.TrimSuffix(dir, "/")
+	return path == dir || strings.HasPrefix(path, dir+"/")
+}
+
 // followSymlink follows the given path
 func followSymlink(path string) string {
 	s, err := os.Readlink(path)